@@ -42,6 +42,11 @@ var addCmd = &cobra.Command{
 
   If the backup directory resolves to the repository directory,
   then obsolete package files are ignored.
+
+  If the package replaces or conflicts with another package in the
+  repository, such as when foo is renamed to foo-bin, the replace_policy
+  option of the profile decides whether the other package is removed,
+  the package is not added, or there is only a warning.
 `,
 	Example:           `  repoctl add -m ./fairsplit-1.0.pkg.tar.gz`,
 	ValidArgsFunction: completeLocalPackageFiles,
@@ -0,0 +1,90 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/repo"
+	"github.com/spf13/cobra"
+)
+
+var checkNoConflicts bool
+
+func init() {
+	MainCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().BoolVar(&checkNoConflicts, "no-conflicts", false, "don't report conflicts with sync repositories")
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [PKGNAME ...]",
+	Short: "Check that the dependencies of packages can be satisfied",
+	Long: `Check that the dependencies of packages in the repository can be satisfied.
+
+  For each package in the database, every dependency must be satisfied by
+  a package in the repository or in one of the sync repositories enabled
+  in the pacman configuration, either by name or by what it provides, and
+  in the required version. If no packages are given, all packages in the
+  database are checked. The following problems are reported:
+
+    "unsatisfied": no package satisfies the dependency
+    "conflict":    the package conflicts with a package from a sync
+                   repository, or the other way round
+
+  Note that conflicts are not necessarily a problem, for example when
+  foo-git conflicts with foo from the official repositories; use
+  --no-conflicts to ignore them.
+
+  The sync databases are used as they are, so run pacman -Sy first if
+  they might be out of date. If any problem is found, or if a given
+  package is not in the database, repoctl exits with a non-zero status,
+  which makes this command suitable for CI.
+`,
+	Example: `  repoctl check
+  repoctl check --no-conflicts foo bar`,
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			failed  int
+			current string
+		)
+		err := Repo.Check(func(err error) error {
+			if errors.Is(err, repo.ErrNotInDatabase) {
+				failed++
+				term.Errorf("%s\n", err)
+				return nil
+			}
+			var derr *repo.DependencyError
+			if !errors.As(err, &derr) {
+				return err
+			}
+			failed++
+			if derr.Package != current {
+				current = derr.Package
+				term.Errorf("%s:\n", derr.Package)
+			}
+			switch derr.Problem {
+			case repo.Unsatisfied:
+				term.Errorff("    @{!r}%s@| %s\n", derr.Problem, derr.Depend)
+			case repo.Conflicting:
+				term.Errorff("    @{!y}%s@| %s (%s)\n", derr.Problem, derr.Other, derr.Depend)
+			}
+			return nil
+		}, !checkNoConflicts, args...)
+		if err != nil {
+			return err
+		}
+		if failed != 0 {
+			return fmt.Errorf("%d problems found", failed)
+		}
+		term.Printf("All dependencies can be satisfied.\n")
+		return nil
+	},
+}
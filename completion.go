@@ -129,6 +129,17 @@ func completeRepoPackageNames(cmd *cobra.Command, args []string, toComplete stri
 }
 
 func completeAURPackageNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Completion does not run the persistent pre-run hook of MainCmd,
+	// so the AUR client of the profile needs to be set up here.
+	configureAUR()
+
+	// With a dump of the AUR, we have all names locally.
+	if names, err := aur.Names(); err == nil {
+		return filterCompletionResults(names, args, toComplete)
+	} else if err != aur.ErrNoDump {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
 	// We don't complete when the argument is too small, because otherwise the
 	// AUR will probably be overloaded.
 	if len(toComplete) < 4 {
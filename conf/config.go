@@ -52,6 +52,13 @@ type Configuration struct {
 	// This allows it to override a possible default value of Quiet.
 	Debug bool `toml:"-"`
 
+	// NoCache causes package files to be read even if they are in the
+	// package metadata cache.
+	NoCache bool `toml:"-"`
+
+	// Offline causes AUR information to be read only from the AUR cache.
+	Offline bool `toml:"-"`
+
 	// When CurrentProfile is specified, it presides over DefaultProfile.
 	// This allows it to override the default, and is what we use for
 	// profile selection from the command line.
@@ -6,12 +6,17 @@ package conf
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/cassava/repoctl/pacman"
 	"github.com/cassava/repoctl/pacman/alpm"
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/goulash/xdg"
 )
 
 // Profile doubles as configuration file format and the global configuration set.
@@ -23,19 +28,63 @@ type Profile struct {
 	database   string
 	repodir    string
 
-	// AddParameters are parameters to add to the repo-add command line.
+	// AddParameters were parameters to add to the repo-add command line.
+	// Deprecated: repoctl writes the database itself, so these are ignored.
 	AddParameters []string `toml:"add_params"`
-	// RemoveParameters are parameters to add to the repo-remove command line.
+	// RemoveParameters were parameters to add to the repo-remove command line.
+	// Deprecated: repoctl writes the database itself, so these are ignored.
 	RemoveParameters []string `toml:"rm_params"`
 	// Packages to ignore when doing AUR related tasks.
 	IgnoreAUR []string `toml:"ignore_aur"`
 	// Require signatures for packages that are added to the database.
 	RequireSignature bool `toml:"require_signature"`
+	// Keyring is the path to the file containing the public keys that
+	// package signatures are verified against. If empty, the keyring is
+	// stored in $XDG_DATA_HOME/repoctl/keyrings/<profile>.asc.
+	Keyring string `toml:"keyring"`
+	// SigningKey is the path to the private key that packages and the
+	// database are signed with. If empty, nothing is signed.
+	SigningKey string `toml:"signing_key"`
+
+	// PacmanRoot is the root of the pacman installation that dependencies
+	// are resolved against, such as a clean chroot. The pacman configuration
+	// and databases are read relative to it. If empty, "/" is used.
+	PacmanRoot string `toml:"pacman_root"`
+	// PacmanDBPath overrides the DBPath of the pacman configuration.
+	PacmanDBPath string `toml:"pacman_dbpath"`
+	// PacmanConf is the path to the pacman configuration. If empty,
+	// etc/pacman.conf in PacmanRoot is used.
+	PacmanConf string `toml:"pacman_conf"`
+	// PreferProviders are the names of packages that are preferred when
+	// a dependency is provided by more than one package.
+	PreferProviders []string `toml:"prefer_providers"`
+	// AURURL is the URL of the AUR that packages are read and downloaded
+	// from, such as an internal mirror. If empty, the official AUR is used.
+	AURURL string `toml:"aur_url"`
+	// AURTimeout is the timeout in seconds for requests to the AUR.
+	// If zero, the default of the aur package is used.
+	AURTimeout int `toml:"aur_timeout"`
+	// AURCacheTTL is how long in seconds responses from the AUR are cached.
+	// If zero, the default of the aur package is used, and if negative,
+	// nothing is cached.
+	AURCacheTTL int `toml:"aur_cache_ttl"`
+	// AURBackend is how packages are read from the AUR: "rpc" asks the AUR
+	// for the packages that are needed, and "dump" downloads the metadata
+	// of all packages. If empty, "rpc" is used.
+	AURBackend string `toml:"aur_backend"`
+	// AURGitRemote is the URL of the git repositories of package bases,
+	// where %s is replaced by the package base. If empty, the git
+	// repositories on the AUR at AURURL are used.
+	AURGitRemote string `toml:"aur_git_remote"`
 
 	// Backup causes older packages to be backed up rather than deleted.
 	Backup bool `toml:"backup"`
 	// BackupDir specifies where old packages are backed up to.
 	BackupDir string `toml:"backup_dir"`
+	// ReplacePolicy specifies what happens when a package that is added
+	// replaces or conflicts with a package in the repository: one of
+	// "warn", "remove", and "refuse". If empty, "warn" is used.
+	ReplacePolicy string `toml:"replace_policy"`
 	// Interactive requires confirmation before deleting and changing the
 	// repository database.
 	Interactive bool `toml:"interactive"`
@@ -95,5 +144,85 @@ func (p *Profile) Init() error {
 		fmt.Fprintf(os.Stderr, "         For example: %s.db.tar.zst\n", filepath.Join(filepath.Dir(p.database), base))
 	}
 
+	switch p.ReplacePolicy {
+	case "", "warn", "remove", "refuse":
+	default:
+		return fmt.Errorf("invalid replace_policy %q: must be one of warn, remove, and refuse", p.ReplacePolicy)
+	}
+
+	switch p.AURBackend {
+	case "", "rpc", "dump":
+	default:
+		return fmt.Errorf("invalid aur_backend %q: must be one of rpc and dump", p.AURBackend)
+	}
+	if p.AURGitRemote != "" && !strings.Contains(p.AURGitRemote, "%s") {
+		return fmt.Errorf("invalid aur_git_remote %q: must contain %%s for the package base", p.AURGitRemote)
+	}
+	if p.AURTimeout < 0 {
+		return fmt.Errorf("invalid aur_timeout %d: must not be negative", p.AURTimeout)
+	}
+
+	// Repoctl no longer calls repo-add and repo-remove.
+	if len(p.AddParameters) != 0 || len(p.RemoveParameters) != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: options \"add_params\" and \"rm_params\" are deprecated; they are ignored.\n")
+	}
+
 	return nil
 }
+
+// PacmanSystem returns the pacman installation that the profile uses
+// for resolving dependencies.
+func (p *Profile) PacmanSystem() *pacman.System {
+	return &pacman.System{
+		Root:     p.PacmanRoot,
+		DBPath:   p.PacmanDBPath,
+		ConfPath: p.PacmanConf,
+	}
+}
+
+// AURClient returns the client that the profile uses for the AUR.
+func (p *Profile) AURClient() *aur.Client {
+	c := aur.NewClient(p.AURURL)
+	if p.AURTimeout > 0 {
+		c.HTTPClient.Timeout = time.Duration(p.AURTimeout) * time.Second
+	}
+	ttl := time.Duration(p.AURCacheTTL) * time.Second
+	if p.AURCacheTTL >= 0 {
+		c.Cache = aur.NewCache(AURCachePath(), ttl)
+	}
+	if p.AURBackend == "dump" {
+		c.Dump = aur.NewDump(AURDumpPath(c.BaseURL), ttl)
+	}
+	return c
+}
+
+// AURCachePath returns the path to the directory that responses from the
+// AUR are cached in. It is shared by all profiles.
+func AURCachePath() string {
+	return xdg.UserCache(path.Join("repoctl", "aur"))
+}
+
+// AURDumpPath returns the path to the directory that the dump of the AUR
+// at baseURL is downloaded to.
+func AURDumpPath(baseURL string) string {
+	host := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return xdg.UserCache(path.Join("repoctl", "aur-dump", host))
+}
+
+// CachePath returns the path to the package metadata cache of the profile
+// with the given name.
+func (p *Profile) CachePath(name string) string {
+	return xdg.UserCache(path.Join("repoctl", "cache", name+".gob"))
+}
+
+// KeyringPath returns the path to the keyring of the profile with the
+// given name.
+func (p *Profile) KeyringPath(name string) string {
+	if p.Keyring != "" {
+		return p.Keyring
+	}
+	return xdg.UserData(path.Join("repoctl", "keyrings", name+".asc"))
+}
@@ -22,12 +22,22 @@ var PropertiesTmpl = template.Must(template.New("properties").Funcs(template.Fun
     {{ range $key, $value := .Profiles }}
     [profiles.{{  $key }}]
         repo = {{ printt $value.Repository }}
-        add_params = {{ printt $value.AddParameters }}
-        rm_params = {{ printt $value.RemoveParameters }}
         ignore_aur = {{ printt $value.IgnoreAUR }}
         require_signature = {{ printt $value.RequireSignature }}
+        keyring = {{ printt $value.Keyring }}
+        signing_key = {{ printt $value.SigningKey }}
+        pacman_root = {{ printt $value.PacmanRoot }}
+        pacman_dbpath = {{ printt $value.PacmanDBPath }}
+        pacman_conf = {{ printt $value.PacmanConf }}
+        prefer_providers = {{ printt $value.PreferProviders }}
+        aur_url = {{ printt $value.AURURL }}
+        aur_timeout = {{ printt $value.AURTimeout }}
+        aur_cache_ttl = {{ printt $value.AURCacheTTL }}
+        aur_backend = {{ printt $value.AURBackend }}
+        aur_git_remote = {{ printt $value.AURGitRemote }}
         backup = {{ printt $value.Backup }}
         backup_dir = {{ printt $value.BackupDir }}
+        replace_policy = {{ printt $value.ReplacePolicy }}
         interactive = {{ printt $value.Interactive }}
         pre_action = {{printt $value.PreAction}}
         post_action = {{ printt $value.PostAction }}
@@ -59,14 +69,6 @@ default_profile = {{ printt .DefaultProfile }}
   # same folder.
   repo = {{ printt $value.Repository }}
 
-  # add_params is the set of parameters that will be passed to repo-add
-  # when it is called. Specify one time for each parameter.
-  add_params = {{ printt $value.AddParameters }}
-
-  # rm_params is the set of parameters that will be passed to repo-remove
-  # when it is called. Specify one time for each parameter.
-  rm_params = {{ printt $value.RemoveParameters }}
-
   # ignore_aur is a set of package names that are ignored in conjunction
   # with AUR related tasks, such as determining if there is an update or not.
   ignore_aur = {{ printt $value.IgnoreAUR }}
@@ -75,6 +77,69 @@ default_profile = {{ printt .DefaultProfile }}
   # also have a signature file.
   require_signature = {{ printt $value.RequireSignature }}
 
+  # keyring is the file containing the public keys that are trusted to
+  # sign packages. Signatures are verified against these keys when
+  # packages are added, and invalid or untrusted signatures are rejected.
+  # Keys are managed with the keys command. If empty, the keyring is
+  # stored in $XDG_DATA_HOME/repoctl/keyrings/<profile>.asc.
+  keyring = {{ printt $value.Keyring }}
+
+  # signing_key is the file containing the private key that is used to
+  # sign packages without signature and the database, whenever it changes.
+  # If require_signature is true, packages without signature are rejected
+  # instead of signed.
+  # It can be exported with gpg --export-secret-keys. If the key is
+  # encrypted, the passphrase is read from $REPOCTL_SIGNING_PASSPHRASE.
+  signing_key = {{ printt $value.SigningKey }}
+
+  # pacman_root is the root of the pacman installation that dependencies
+  # are resolved against, which is the system itself by default. Setting
+  # this to a clean chroot or the root of another architecture means that
+  # its configuration and installed packages are used instead.
+  pacman_root = {{ printt $value.PacmanRoot }}
+
+  # pacman_dbpath overrides the DBPath of the pacman configuration.
+  pacman_dbpath = {{ printt $value.PacmanDBPath }}
+
+  # pacman_conf is the pacman configuration file that is used. If empty,
+  # etc/pacman.conf in pacman_root is used.
+  pacman_conf = {{ printt $value.PacmanConf }}
+
+  # prefer_providers is a list of package names that are preferred when
+  # a dependency is provided by several packages, such as when resolving
+  # dependencies for the down command. Otherwise, installed packages are
+  # preferred, then packages from repositories, and then AUR.
+  prefer_providers = {{ printt $value.PreferProviders }}
+
+  # aur_url is the URL of the AUR that packages are read and downloaded
+  # from, such as an internal mirror. If empty, https://aur.archlinux.org
+  # is used.
+  aur_url = {{ printt $value.AURURL }}
+
+  # aur_timeout is the timeout in seconds for each request to the AUR.
+  # Failed requests are retried a few times. If 0, 30 seconds are used.
+  aur_timeout = {{ printt $value.AURTimeout }}
+
+  # aur_cache_ttl is how long in seconds responses from the AUR are cached
+  # in $XDG_CACHE_HOME/repoctl/aur. Older responses are only used if the AUR
+  # cannot be reached, or with --offline. If 0, five minutes are used, and
+  # if negative, nothing is cached.
+  aur_cache_ttl = {{ printt $value.AURCacheTTL }}
+
+  # aur_backend specifies how packages are read from the AUR. Can be one of:
+  # - "rpc": ask the AUR for the packages that are needed.
+  # - "dump": download the metadata of all packages that the AUR publishes,
+  #   and read and search it locally. It is downloaded again after
+  #   aur_cache_ttl, but only if it changed. This is faster for large
+  #   repositories, and completion of package names works offline.
+  # If empty, "rpc" is used.
+  aur_backend = {{ printt $value.AURBackend }}
+
+  # aur_git_remote is the URL of the git repositories that the down command
+  # clones with --git, where %s is replaced by the package base, such as
+  # "/srv/aur/%s.git". If empty, the git repositories on the AUR are used.
+  aur_git_remote = {{ printt $value.AURGitRemote }}
+
   # backup specifies whether package files should be backed up or deleted.
   # If it is set to false, then obsolete package files are deleted.
   backup = {{ printt $value.Backup }}
@@ -86,6 +151,15 @@ default_profile = {{ printt .DefaultProfile }}
   #   are effectively ignored by repoctl, if backup is true.
   backup_dir = {{ printt $value.BackupDir }}
 
+  # replace_policy specifies what happens when a package is added that
+  # replaces or conflicts with a package in the repository, such as when
+  # foo is renamed to foo-bin. Can be one of:
+  # - "warn": add the package, but warn that the old one remains.
+  # - "remove": remove the old package, as if with the remove command.
+  # - "refuse": do not add the package.
+  # If empty, "warn" is used.
+  replace_policy = {{ printt $value.ReplacePolicy }}
+
   # interactive specifies that repoctl should ask before doing anything
   # destructive.
   interactive = {{ printt $value.Interactive }}
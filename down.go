@@ -5,8 +5,13 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman/aur"
@@ -21,10 +26,13 @@ var (
 	downDryRun   bool
 	downClobber  bool
 	downExtract  bool
+	downGit      bool
 	downUpgrades bool
 	downAll      bool
 	downRecurse  bool
 	downOrder    string
+	downFormat   string
+	downBreak    []string
 )
 
 func init() {
@@ -36,10 +44,14 @@ func init() {
 	downCmd.Flags().BoolVarP(&downDryRun, "dry-run", "n", false, "don't download any packages")
 	downCmd.Flags().BoolVarP(&downClobber, "clobber", "l", false, "delete conflicting files and folders")
 	downCmd.Flags().BoolVarP(&downExtract, "extract", "e", true, "extract the downloaded tarballs")
+	downCmd.Flags().BoolVarP(&downGit, "git", "g", false, "clone or update git repositories instead of downloading tarballs")
 	downCmd.Flags().BoolVarP(&downUpgrades, "upgrades", "u", false, "download tarballs for all upgrades")
 	downCmd.Flags().BoolVarP(&downRecurse, "recursive", "r", false, "download any necessary dependencies")
 	downCmd.Flags().StringVarP(&downOrder, "order", "o", "", "write the order of compilation based on dependency tree into a file, implies -r")
+	downCmd.Flags().StringVar(&downFormat, "order-format", "list", "format of the build-order (list|levels|json|make)")
+	downCmd.RegisterFlagCompletionFunc("order-format", cobra.FixedCompletions([]string{"list", "levels", "json", "make"}, cobra.ShellCompDirectiveNoFileComp))
 	downCmd.Flags().BoolVarP(&downAll, "all", "a", false, "download tarballs for all packages in database")
+	downCmd.Flags().StringSliceVar(&downBreak, "break-cycle", nil, "ignore make and check dependencies of PKG on DEP, given as PKG:DEP")
 }
 
 var downCmd = &cobra.Command{
@@ -76,24 +88,52 @@ var downCmd = &cobra.Command{
   You can just output the correct build order by adding the -n flag to
   prevent downloading of tarballs.
 
-  Caveats:
+  With --git, the git repositories of the packages are cloned instead of
+  downloading tarballs. Repositories that have been cloned before are
+  fetched and fast-forwarded, and the previous HEAD is kept in ORIG_HEAD,
+  so that the changes can be reviewed before building:
 
-  1. Automatic dependency resolution does not currently handle version
-     resolution or library specifications, as noted in the Arch wiki at:
-       https://wiki.archlinux.org/index.php/PKGBUILD#Dependencies
+    repoctl down --git -u
+    git -C foo log -p ORIG_HEAD..HEAD
 
-  2. Package dependencies are not resolved that are only "provided"
-     by other packages. Here, we currently print an "unknown package" warning.
+  The repositories are cloned from the AUR, unless the aur_git_remote
+  option of the profile specifies otherwise, such as a local mirror.
 
-	 For example, at the time of writing firefox56 requires mime-types.
-	 This package does not exist, but is provided by other packages.
-	 We can check this with:
-	   repoctl query $(repoctl search -q mime-types)
-	 Which leads us to see that mailcap-mime-types provides mime-types.
-	 This caveat will be resolved in the future.
+  The build order can be written in several formats with --order-format:
+
+    list     one package per line, in the order they should be built
+    levels   one level per line; the packages in a level only depend
+             on packages in earlier levels, and can be built in parallel
+    json     the levels as a JSON array of arrays of package names
+    make     a Makefile with one target per package, which runs
+             $(MAKEPKG) in the package directory after the AUR packages
+             it needs have been built; MAKEPKG is "makepkg -si" unless
+             it is given on the make command line
+
+  The --order-format flag can only be used together with -o.
+
+  Dependencies are resolved in the same way as pacman does, which means
+  that they can also be satisfied by packages that "provide" them, and
+  that version restrictions are respected. If several packages provide
+  a dependency, then the packages in the prefer_providers option of the
+  profile are preferred, followed by installed packages, packages from
+  repositories, and finally packages from AUR.
+
+  If a version restriction cannot be satisfied, such as when a package
+  requires foo>=2 but AUR only has foo 1.2, then nothing is downloaded.
+  Dependencies that cannot be found at all are reported as unknown.
+
+  If packages depend on each other in a cycle, there is no order in
+  which they can be built, and nothing is downloaded. Such a cycle can
+  often be broken by ignoring the make and check dependencies of one
+  package on another with --break-cycle PKG:DEP, and building DEP
+  without the features that need PKG first.
 `,
 	Example: `  repoctl down -u
-  repoctl down -o build-order.txt -u`,
+  repoctl down -o build-order.txt -u
+  repoctl down -o build-order.json --order-format=json -u
+  repoctl down -r --break-cycle foo:bar foo
+  repoctl down --git -u`,
 	ValidArgsFunction: completeAURPackageNames,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if downAll || downUpgrades {
@@ -108,6 +148,15 @@ var downCmd = &cobra.Command{
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		switch downFormat {
+		case "list", "levels", "json", "make":
+		default:
+			return fmt.Errorf("unknown build-order format: %s", downFormat)
+		}
+		if cmd.Flags().Changed("order-format") && downOrder == "" {
+			return fmt.Errorf("--order-format requires -o to write the build order")
+		}
+
 		// First, populate the initial list of packages to download.
 		var list []string
 		if downAll {
@@ -127,47 +176,97 @@ var downCmd = &cobra.Command{
 		} else {
 			list = args
 		}
+		return downloadPackages(list)
+	},
+}
 
-		// If no dependencies are wanted, then get to it right away:
-		if !downRecurse && downOrder == "" {
-			// There's not much point to a try run here, but we should respect
-			// the option nevertheless.
-			if downDryRun {
-				return nil
-			}
-			return repo.Download(downDest, downExtract, downClobber, list)
-		}
-
-		// Otherwise, get the dependency list and download the packages:
-		aps, err := downDependencies(list)
-		if err != nil {
-			return err
-		}
-		// Don't download any packages if dry run is activated.
+// downloadPackages downloads the AUR packages in list, according to the
+// options of the down command. It is shared with other commands that
+// download packages, such as rebuilds --down.
+func downloadPackages(list []string) error {
+	// If no dependencies are wanted, then get to it right away:
+	if !downRecurse && downOrder == "" {
+		// There's not much point to a try run here, but we should respect
+		// the option nevertheless.
 		if downDryRun {
 			return nil
 		}
-		return repo.DownloadPackages(aps, downDest, downExtract, downClobber)
-	},
+		if downGit {
+			return repo.DownloadGit(downDest, aurGitRemote(), downClobber, list)
+		}
+		return repo.Download(downDest, downExtract, downClobber, list)
+	}
+
+	// Otherwise, get the dependency list and download the packages:
+	aps, err := downDependencies(list)
+	if err != nil {
+		return err
+	}
+	// Don't download any packages if dry run is activated.
+	if downDryRun {
+		return nil
+	}
+	if downGit {
+		return repo.DownloadGitPackages(aps, downDest, aurGitRemote(), downClobber)
+	}
+	return repo.DownloadPackages(aps, downDest, downExtract, downClobber)
+}
+
+// dependencyGraph returns the dependency graph of packages, printing
+// each dependency that cannot be satisfied.
+func dependencyGraph(opts repo.GraphOptions, packages []string) (*graph.Graph, error) {
+	opts.Prefer = preferProviders()
+	g, err := repo.DependencyGraph(pacmanSystem(), opts, packages)
+	var unsatisfied graph.UnsatisfiedErrors
+	if errors.As(err, &unsatisfied) {
+		for _, e := range unsatisfied {
+			term.Errorf("Error: %s\n", e)
+		}
+		return nil, fmt.Errorf("cannot resolve dependencies")
+	}
+	return g, err
 }
 
 func downDependencies(packages []string) (aur.Packages, error) {
-	g, err := repo.DependencyGraph(packages)
+	g, err := dependencyGraph(repo.GraphOptions{}, packages)
 	if err != nil {
 		return nil, err
 	}
-	_, aps, ups := graph.Dependencies(g)
+	for _, s := range downBreak {
+		pkg, dep, ok := strings.Cut(s, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid argument to --break-cycle: %s", s)
+		}
+		if !g.IgnoreDependency(pkg, dep, graph.MakeDepends|graph.CheckDepends) {
+			term.Warnf("Warning: %s has no make or check dependency on %s\n", pkg, dep)
+		}
+	}
+	_, aps, ups, err := graph.Dependencies(g)
+	var cycles *graph.CycleError
+	if errors.As(err, &cycles) {
+		for _, c := range cycles.Cycles {
+			term.Errorf("Error: dependency cycle between %s:\n", strings.Join(c.Packages, ", "))
+			for _, e := range c.Edges {
+				term.Errorff("         %s\n", e)
+			}
+		}
+		return nil, fmt.Errorf("cannot determine build order")
+	} else if err != nil {
+		return nil, err
+	}
 	if downOrder != "" {
 		term.Debugf("Writing build-order to: %s\n", downOrder)
 		f, err := os.Create(downOrder)
 		if err != nil {
-			return nil, fmt.Errorf("cannot write build-order to %s: %w\n", downOrder, err)
+			return nil, fmt.Errorf("cannot write build-order to %s: %w", downOrder, err)
 		}
-
-		for i := len(aps); i != 0; i-- {
-			fmt.Fprintln(f, aps[i-1].Name)
+		err = writeBuildOrder(f, g, aps)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot write build-order to %s: %w", downOrder, err)
 		}
-		f.Close()
 	}
 	for _, u := range ups {
 		term.Warnf("Warning: unknown package %s\n", u)
@@ -179,3 +278,81 @@ func downDependencies(packages []string) (aur.Packages, error) {
 	}
 	return aps, nil
 }
+
+// writeBuildOrder writes the build order of the AUR packages in g to w,
+// in the format given by downFormat. The packages aps are in the order
+// returned by graph.Dependencies, which is the reverse build order.
+func writeBuildOrder(w io.Writer, g *graph.Graph, aps aur.Packages) error {
+	if downFormat == "list" {
+		for i := len(aps); i != 0; i-- {
+			if _, err := fmt.Fprintln(w, aps[i-1].Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	levels, err := graph.BuildLevels(g)
+	if err != nil {
+		return err
+	}
+	names := make([][]string, len(levels))
+	for i, lvl := range levels {
+		names[i] = make([]string, len(lvl))
+		for j, p := range lvl {
+			names[i][j] = p.Name
+		}
+	}
+
+	switch downFormat {
+	case "levels":
+		for _, lvl := range names {
+			if _, err := fmt.Fprintln(w, strings.Join(lvl, " ")); err != nil {
+				return err
+			}
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(names)
+	case "make":
+		var all []string
+		for _, lvl := range names {
+			all = append(all, lvl...)
+		}
+		bases := make(map[string]string)
+		for _, p := range aps {
+			bases[p.Name] = p.PackageBase
+			if p.PackageBase == "" {
+				bases[p.Name] = p.Name
+			}
+		}
+
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "MAKEPKG ?= makepkg -si\n\n")
+		fmt.Fprintf(&buf, ".PHONY: all %s\n\n", strings.Join(all, " "))
+		fmt.Fprintf(&buf, "all: %s\n", strings.Join(all, " "))
+
+		// The packages of a split package are built together, by the
+		// target of the first of them; the others depend on it.
+		built := make(map[string]string)
+		for i, lvl := range names {
+			fmt.Fprintf(&buf, "\n# Level %d\n", i+1)
+			for _, name := range lvl {
+				deps := graph.AURDependencies(g, name)
+				base := bases[name]
+				if first, ok := built[base]; ok {
+					deps = append(deps, first)
+					fmt.Fprintln(&buf, strings.TrimSpace(name+": "+strings.Join(deps, " ")))
+					continue
+				}
+				built[base] = name
+				fmt.Fprintln(&buf, strings.TrimSpace(name+": "+strings.Join(deps, " ")))
+				fmt.Fprintf(&buf, "\tcd %s && $(MAKEPKG)\n", filepath.Join(downDest, base))
+			}
+		}
+		_, err = io.WriteString(w, buf.String())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/spf13/cobra"
+)
+
+var filesOwns bool
+
+func init() {
+	MainCmd.AddCommand(filesCmd)
+
+	filesCmd.Flags().BoolVarP(&filesOwns, "owns", "o", false, "show which packages own the given files")
+}
+
+var filesCmd = &cobra.Command{
+	Use:   "files [--owns] {PKGNAME ... | FILE ...}",
+	Short: "Query the files database of the repository",
+	Long: `Query the files database of the repository.
+
+  Whenever packages are added to or removed from the repository, repoctl
+  also updates the files database, which contains the list of files of each
+  package. This is the database that pacman -F uses.
+
+  Without flags, the files of each package given are listed. If no packages
+  are given, then the files of all packages in the repository are listed.
+
+  With the --owns flag, the arguments are paths of files, and the packages
+  that contain these files are shown. If a path contains no slash, then it
+  is matched against the name of each file, as pacman -F does.
+
+  If the files database does not exist yet, you can create it by running
+  the reset command.
+`,
+	Example: `  repoctl files fairsplit
+  repoctl files -o /usr/bin/fairsplit
+  repoctl files -o fairsplit`,
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exceptQuiet()
+
+		pkgs, err := Repo.ReadFilesDatabase()
+		if err != nil {
+			return fmt.Errorf("cannot read files database (try running reset): %w", err)
+		}
+		sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+
+		if filesOwns {
+			if len(args) == 0 {
+				return fmt.Errorf("require at least one file with --owns")
+			}
+			for _, f := range args {
+				var found bool
+				for _, p := range pkgs {
+					if p.Owns(f) {
+						found = true
+						term.Printf("%s is owned by @{!m}%s/@{!w}%s @{!g}%s\n", f, Repo.Name(), p.Name, p.Version)
+					}
+				}
+				if !found {
+					term.Warnf("Warning: no package owns %s\n", f)
+				}
+			}
+			return nil
+		}
+
+		want := make(map[string]bool)
+		for _, n := range args {
+			want[n] = true
+		}
+		for _, p := range pkgs {
+			if len(args) != 0 && !want[p.Name] {
+				continue
+			}
+			delete(want, p.Name)
+			for _, f := range p.Files {
+				term.Printf("%s /%s\n", p.Name, f)
+			}
+		}
+		unknown := make([]string, 0, len(want))
+		for n := range want {
+			unknown = append(unknown, n)
+		}
+		sort.Strings(unknown)
+		for _, n := range unknown {
+			term.Warnf("Warning: unknown package %s\n", n)
+		}
+		return nil
+	},
+}
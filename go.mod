@@ -6,21 +6,23 @@ toolchain go1.22.1
 
 require (
 	github.com/BurntSushi/toml v1.3.2
+	github.com/ProtonMail/go-crypto v1.1.6
 	github.com/goulash/archive v1.3.2
 	github.com/goulash/color v1.0.0
 	github.com/goulash/errs v1.0.0
 	github.com/goulash/osutil v1.0.2
 	github.com/goulash/pr v1.0.0
 	github.com/goulash/xdg v1.0.0
+	github.com/klauspost/compress v1.17.7
 	github.com/spf13/cobra v1.8.0
+	github.com/ulikunitz/xz v0.5.11
 	gonum.org/v1/gonum v0.15.0
 )
 
 require (
+	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/klauspost/compress v1.17.7 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/ulikunitz/xz v0.5.11 // indirect
 	golang.org/x/crypto v0.21.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/term v0.18.0 // indirect
@@ -0,0 +1,82 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cassava/repoctl/pacman/graph"
+	"github.com/cassava/repoctl/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat    string
+	graphInstalled bool
+	graphFull      bool
+)
+
+func init() {
+	MainCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", "dot", "output format (dot|json|mermaid)")
+	graphCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"dot", "json", "mermaid"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	graphCmd.Flags().BoolVarP(&graphInstalled, "installed", "i", true, "include installed packages")
+	graphCmd.Flags().BoolVar(&graphFull, "full", false, "resolve dependencies of repository packages too")
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph PKGNAME...",
+	Short: "Export the dependency graph of AUR packages",
+	Long: `Export the dependency graph of AUR packages.
+
+  The dependencies of the given AUR packages are resolved in the same way
+  as for the down command, and the resulting graph is written to standard
+  output in one of the following formats:
+
+    dot       Graphviz DOT language, for example for dot -Tsvg
+    json      a list of nodes and a list of edges
+    mermaid   Mermaid flowchart, for example for Markdown documents
+
+  Packages are colored by where they come from: AUR, the repositories,
+  installed, or unknown. Edges that are only make or check dependencies
+  are dashed.
+
+  By default, installed packages are included, but the dependencies of
+  packages from the repositories are not resolved, since pacman takes
+  care of them. Use --full to see those too, but expect a large graph.
+`,
+	Example: `  repoctl graph repoctl | dot -Tsvg > repoctl.svg
+  repoctl graph --format=mermaid --installed=false foo bar`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeAURPackageNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var write func(io.Writer, *graph.Graph) error
+		switch graphFormat {
+		case "dot":
+			write = graph.WriteDOT
+		case "json":
+			write = graph.WriteJSON
+		case "mermaid":
+			write = graph.WriteMermaid
+		default:
+			return fmt.Errorf("unknown graph format: %s", graphFormat)
+		}
+
+		g, err := dependencyGraph(repo.GraphOptions{
+			Installed: graphInstalled,
+			Full:      graphFull,
+		}, args)
+		if err != nil {
+			return err
+		}
+		return write(os.Stdout, g)
+	},
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	MainCmd.AddCommand(infoCmd)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info PKGFILE ...",
+	Short: "Show information contained in package files",
+	Long: `Show the information contained in package files.
+
+  For each package file, the following is shown:
+
+    - the package metadata from .PKGINFO,
+    - the build environment from .BUILDINFO, such as the build directory,
+      the makepkg options, and the packages installed during the build,
+    - the files that the package contains.
+
+  Metadata properties that are empty are not shown. Packages that were
+  built before .BUILDINFO existed have no build environment.
+`,
+	Example:               `  repoctl info fairsplit-1.0-1-any.pkg.tar.zst`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+	ValidArgsFunction:     completeLocalPackageFiles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exceptQuiet()
+
+		for i, f := range args {
+			in, err := pacman.Inspect(f)
+			if err != nil {
+				return err
+			}
+			if i != 0 {
+				term.Printf("\n")
+			}
+			term.Printf("@{!w}%s @{!g}%s\n@|", in.Name, in.Version)
+			term.Printf("@.%s", formatInspection(in))
+		}
+		return nil
+	},
+}
+
+func formatInspection(in *pacman.Inspection) string {
+	var buf strings.Builder
+	field := func(key string, values ...string) {
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			return
+		}
+		fmt.Fprintf(&buf, "    %s: %s\n", key, strings.Join(values, " "))
+	}
+	list := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(&buf, "    %s:\n", key)
+		for _, v := range values {
+			fmt.Fprintf(&buf, "        %s\n", v)
+		}
+	}
+
+	p := in.Package
+	field("Filename", p.Filename)
+	field("Name", p.Name)
+	if p.Base != p.Name {
+		field("Base Name", p.Base)
+	}
+	field("Version", p.Version)
+	field("Description", p.Description)
+	field("Architecture", p.Arch)
+	field("URL", p.URL)
+	field("Licenses", p.License...)
+	field("Groups", p.Groups...)
+	field("Provides", p.Provides...)
+	field("Conflicts", p.Conflicts...)
+	field("Replaces", p.Replaces...)
+	field("Dependencies", p.Depends...)
+	list("Optional Dependencies", p.OptionalDepends)
+	field("Build Dependencies", p.MakeDepends...)
+	field("Check Dependencies", p.CheckDepends...)
+	list("Backup Files", p.Backups)
+	field("Installed Size", fmt.Sprintf("%d bytes", p.InstalledSize))
+	field("Packager", p.Packager)
+	field("Build Date", p.BuildDate.String())
+
+	if bi := in.BuildInfo; bi != nil {
+		fmt.Fprintf(&buf, "\n    Build Environment:\n")
+		field("Build Directory", bi.BuildDir)
+		field("Start Directory", bi.StartDir)
+		if bi.BuildTool != "" {
+			field("Build Tool", bi.BuildTool, bi.BuildToolVersion)
+		}
+		field("PKGBUILD SHA256", bi.PKGBUILDSHA256Sum)
+		field("Environment", bi.BuildEnv...)
+		field("Options", bi.Options...)
+		list("Installed", bi.Installed)
+	}
+
+	if len(in.Files) != 0 {
+		fmt.Fprintf(&buf, "\n")
+		list("Files", in.Files)
+	}
+	return buf.String()
+}
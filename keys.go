@@ -0,0 +1,150 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman/pgp"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	MainCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysListCmd)
+	keysCmd.AddCommand(keysImportCmd)
+	keysCmd.AddCommand(keysRemoveCmd)
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys {list | import | remove} [options]",
+	Short: "Manage keys trusted to sign packages",
+	Long: `Manage the keyring of public keys that are trusted to sign packages.
+
+  When packages are added to the repository, their signatures are verified
+  against the keyring of the current profile. Packages with signatures that
+  are invalid or made by keys that are not in the keyring are rejected.
+  If require_signature is set, packages must also have a signature.
+
+  Each profile has its own keyring, which is stored by default in:
+
+    $XDG_DATA_HOME/repoctl/keyrings/<profile>.asc
+
+  This can be changed with the keyring option in the profile.
+`,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:                   "list",
+	Short:                 "List trusted keys",
+	Args:                  cobra.ExactArgs(0),
+	DisableFlagsInUseLine: true,
+	ValidArgsFunction:     completeNoFiles,
+	PreRunE:               ProfileInit,
+	PostRunE:              ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exceptQuiet()
+
+		kr, err := Repo.ReadKeyring()
+		if err != nil {
+			return err
+		}
+		if kr.Len() == 0 {
+			term.Printf("Keyring is empty: %s\n", kr.Path())
+			return nil
+		}
+		for _, k := range kr.Keys() {
+			printKey(k)
+		}
+		return nil
+	},
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import FILE ...",
+	Short: "Import public keys into the keyring",
+	Long: `Import public keys into the keyring.
+
+  Keys can be armored or binary, as exported by gpg. If FILE is "-",
+  keys are read from standard input.
+`,
+	Example: `  gpg --export --armor you@example.com | repoctl keys import -
+  repoctl keys import packager.asc`,
+	Args:     cobra.MinimumNArgs(1),
+	PreRunE:  ProfileInit,
+	PostRunE: ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := Repo.ReadKeyring()
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			keys, err := importKeys(kr, arg)
+			if err != nil {
+				return fmt.Errorf("cannot import keys from %s: %w", arg, err)
+			}
+			for _, k := range keys {
+				term.Printf("Importing key: %s\n", k)
+			}
+		}
+		return kr.Write()
+	},
+}
+
+func importKeys(kr *pgp.Keyring, filename string) ([]*pgp.Key, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return kr.Import(r)
+}
+
+var keysRemoveCmd = &cobra.Command{
+	Use:   "remove KEYID ...",
+	Short: "Remove keys from the keyring",
+	Long: `Remove keys from the keyring.
+
+  Keys can be identified by their fingerprint or their key ID, which is
+  the end of the fingerprint, such as the last 16 characters.
+`,
+	Args:     cobra.MinimumNArgs(1),
+	PreRunE:  ProfileInit,
+	PostRunE: ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kr, err := Repo.ReadKeyring()
+		if err != nil {
+			return err
+		}
+		n := kr.Len()
+		for _, arg := range args {
+			keys := kr.Remove(arg)
+			if len(keys) == 0 {
+				term.Warnf("Warning: no key matching %s in keyring\n", arg)
+			}
+			for _, k := range keys {
+				term.Printf("Removing key: %s\n", k)
+			}
+		}
+		if kr.Len() == n {
+			return nil
+		}
+		return kr.Write()
+	},
+}
+
+func printKey(k *pgp.Key) {
+	term.Printf("@{!w}%s@| (created %s)\n", k.Fingerprint, k.Created.Format("2006-01-02"))
+	for _, id := range k.UserIDs {
+		term.Printf("    %s\n", id)
+	}
+}
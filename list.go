@@ -12,6 +12,7 @@ import (
 
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/aur"
 	"github.com/cassava/repoctl/pacman/meta"
 	"github.com/goulash/pr"
 	"github.com/spf13/cobra"
@@ -103,8 +104,10 @@ var listCmd = &cobra.Command{
 			}
 		}
 
+		var aurpkgs aur.Packages
 		pkgs, err := Repo.ListMeta(nil, listSynchronize, func(mp pacman.AnyPackage) string {
 			p := mp.(*meta.Package)
+			aurpkgs = append(aurpkgs, p.AUR)
 			if regex != nil && !regex.MatchString(p.PkgName()) {
 				return ""
 			}
@@ -155,6 +158,8 @@ var listCmd = &cobra.Command{
 			return err
 		}
 
+		warnStaleAUR(aurpkgs)
+
 		// Print packages to stdout
 		sort.Strings(pkgs)
 		printSet(pkgs, "", Conf.Columnate)
@@ -5,13 +5,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 
 	"github.com/cassava/repoctl/conf"
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/aur"
 	"github.com/cassava/repoctl/repo"
 	"github.com/spf13/cobra"
 )
@@ -51,6 +55,8 @@ func init() {
 	MainCmd.PersistentFlags().BoolVarP(&Conf.Columnate, "columns", "s", c.Columnate, "show items in columns rather than lines")
 	MainCmd.PersistentFlags().BoolVarP(&Conf.Quiet, "quiet", "q", c.Quiet, "show minimal amount of information")
 	MainCmd.PersistentFlags().BoolVar(&Conf.Debug, "debug", c.Debug, "show unnecessary debugging information")
+	MainCmd.PersistentFlags().BoolVar(&Conf.NoCache, "no-cache", false, "read package files instead of using the metadata cache")
+	MainCmd.PersistentFlags().BoolVar(&Conf.Offline, "offline", false, "read AUR information only from the AUR cache")
 	MainCmd.PersistentFlags().Var(term.Formatter, "color", "when to use color (auto|never|always)")
 }
 
@@ -110,6 +116,7 @@ And by viewing the status of your repository:
 		cmd.SilenceUsage = true
 
 		configureTerm()
+		configureAUR()
 
 		return nil
 	},
@@ -140,7 +147,17 @@ func exceptQuiet() {
 
 // main loads the configuration and executes the primary command.
 func main() {
-	err := MainCmd.Execute()
+	// The first interrupt cancels requests to AUR, so that repoctl can
+	// stop cleanly; a second one terminates repoctl immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	aur.DefaultContext = ctx
+
+	err := MainCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		// If this is an ExecError, we deal with it specially:
 		if e, ok := err.(*ExecError); ok {
@@ -196,6 +213,71 @@ func ProfileInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// pacmanSystem returns the pacman installation of the current profile.
+// This also works for commands that do not require a profile, in which
+// case the host system is returned if there is no profile.
+func pacmanSystem() *pacman.System {
+	if Repo != nil {
+		return Repo.System
+	}
+	if p, _, _ := Conf.SelectProfile(); p != nil {
+		return p.PacmanSystem()
+	}
+	return pacman.DefaultSystem()
+}
+
+// preferProviders returns the packages that the current profile prefers
+// when a dependency is provided by several packages. Like pacmanSystem,
+// this also works for commands that do not require a profile.
+func preferProviders() []string {
+	if Repo != nil {
+		return Repo.PreferProviders
+	}
+	if p, _, _ := Conf.SelectProfile(); p != nil {
+		return p.PreferProviders
+	}
+	return nil
+}
+
+// configureAUR sets the AUR client that is used for all AUR related tasks
+// to the one of the current profile. Like pacmanSystem, this also works
+// for commands that do not require a profile.
+func configureAUR() {
+	p, _, _ := Conf.SelectProfile()
+	if p == nil {
+		p = conf.DefaultProfile()
+	}
+	aur.DefaultClient = p.AURClient()
+	aur.DefaultClient.Offline = Conf.Offline
+}
+
+// warnStaleAUR warns if the AUR information of any of the packages might
+// be out of date, because it was read from the AUR cache, either because
+// of --offline or because the AUR could not be reached.
+func warnStaleAUR(pkgs aur.Packages) {
+	n := 0
+	for _, p := range pkgs {
+		if p != nil && p.Stale() {
+			n++
+		}
+	}
+	if n == 1 {
+		term.Warnf("Warning: AUR information for 1 package may be out of date.\n")
+	} else if n > 1 {
+		term.Warnf("Warning: AUR information for %d packages may be out of date.\n", n)
+	}
+}
+
+// aurGitRemote returns the URL template of the git repositories of AUR
+// packages of the current profile. Like pacmanSystem, this also works for
+// commands that do not require a profile.
+func aurGitRemote() string {
+	if p, _, _ := Conf.SelectProfile(); p != nil {
+		return p.AURGitRemote
+	}
+	return ""
+}
+
 // ProfileTeardown should be used as the PostRunE part of every command
 // that needs to make use of the profile or the Repo.
 func ProfileTeardown(cmd *cobra.Command, args []string) error {
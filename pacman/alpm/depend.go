@@ -0,0 +1,184 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package alpm
+
+import "strings"
+
+// Operator is the comparison operator of a versioned dependency.
+type Operator int
+
+const (
+	// Any means that any version satisfies the dependency.
+	Any Operator = iota
+	Equal
+	Greater
+	GreaterEqual
+	Less
+	LessEqual
+)
+
+// String returns the operator as it is written in a dependency,
+// or the empty string for Any.
+func (op Operator) String() string {
+	switch op {
+	case Equal:
+		return "="
+	case Greater:
+		return ">"
+	case GreaterEqual:
+		return ">="
+	case Less:
+		return "<"
+	case LessEqual:
+		return "<="
+	default:
+		return ""
+	}
+}
+
+// Compare returns whether version satisfies the operator with respect
+// to target, for example whether version >= target. Any is satisfied
+// by every version.
+func (op Operator) Compare(version, target string) bool {
+	if op == Any {
+		return true
+	}
+	c := VerCmp(version, target)
+	switch op {
+	case Equal:
+		return c == 0
+	case Greater:
+		return c > 0
+	case GreaterEqual:
+		return c >= 0
+	case Less:
+		return c < 0
+	case LessEqual:
+		return c <= 0
+	default:
+		return false
+	}
+}
+
+// Depend is a dependency expression, as found in the depends, provides,
+// conflicts, and replaces fields of a package. For example:
+//
+//	glibc
+//	python>=3.11
+//	libalpm.so=15-64
+//	python-bar: for bar support
+//
+// The last form, with a description, is used for optional dependencies.
+// Multiple constraints on the same package are expressed by multiple
+// Depends, such as python>=3.11 and python<3.12.
+type Depend struct {
+	Name        string
+	Op          Operator
+	Version     string
+	Description string
+}
+
+// ParseDepend parses a dependency expression in the same way as pacman.
+// Anything after the first ": " is the description, and the first of the
+// characters "<>=" starts the operator.
+func ParseDepend(s string) Depend {
+	var d Depend
+	if i := strings.Index(s, ": "); i != -1 {
+		s, d.Description = s[:i], s[i+2:]
+	}
+
+	i := strings.IndexAny(s, "<>=")
+	if i == -1 {
+		d.Name = s
+		return d
+	}
+	d.Name, s = s[:i], s[i:]
+	switch {
+	case strings.HasPrefix(s, ">="):
+		d.Op, d.Version = GreaterEqual, s[2:]
+	case strings.HasPrefix(s, "<="):
+		d.Op, d.Version = LessEqual, s[2:]
+	case strings.HasPrefix(s, "="):
+		d.Op, d.Version = Equal, s[1:]
+	case strings.HasPrefix(s, ">"):
+		d.Op, d.Version = Greater, s[1:]
+	case strings.HasPrefix(s, "<"):
+		d.Op, d.Version = Less, s[1:]
+	}
+	return d
+}
+
+// ParseDepends parses each of the dependency expressions in ss.
+func ParseDepends(ss []string) []Depend {
+	if ss == nil {
+		return nil
+	}
+	deps := make([]Depend, len(ss))
+	for i, s := range ss {
+		deps[i] = ParseDepend(s)
+	}
+	return deps
+}
+
+// DependNames returns the names of the dependency expressions in ss,
+// without version constraints and descriptions.
+func DependNames(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	names := make([]string, len(ss))
+	for i, s := range ss {
+		names[i] = ParseDepend(s).Name
+	}
+	return names
+}
+
+// String returns the dependency expression in the same format that
+// ParseDepend reads.
+func (d Depend) String() string {
+	s := d.Name
+	if d.Op != Any {
+		s += d.Op.String() + d.Version
+	}
+	if d.Description != "" {
+		s += ": " + d.Description
+	}
+	return s
+}
+
+// IsVersioned returns whether the dependency constrains the version.
+func (d Depend) IsVersioned() bool { return d.Op != Any }
+
+// IsLibrary returns whether the dependency refers to a shared library,
+// such as libalpm.so, instead of a package. Such dependencies are
+// satisfied by the provides of a package, which makepkg adds
+// automatically, such as libalpm.so=15-64.
+func (d Depend) IsLibrary() bool {
+	return strings.HasSuffix(d.Name, ".so") || strings.Contains(d.Name, ".so.")
+}
+
+// SatisfiedBy returns whether a package with the given name, version,
+// and provides satisfies the dependency.
+//
+// As in pacman, a provision only satisfies a versioned dependency if
+// it has a version itself, such as foo=1.2.
+func (d Depend) SatisfiedBy(name, version string, provides []string) bool {
+	if name == d.Name && d.Op.Compare(version, d.Version) {
+		return true
+	}
+	for _, s := range provides {
+		p := ParseDepend(s)
+		if p.Name != d.Name {
+			continue
+		}
+		if d.Op == Any {
+			return true
+		}
+		if p.Op == Equal && d.Op.Compare(p.Version, d.Version) {
+			return true
+		}
+	}
+	return false
+}
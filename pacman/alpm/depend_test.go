@@ -0,0 +1,70 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package alpm
+
+import "testing"
+
+func TestParseDepend(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Depend
+	}{
+		{"glibc", Depend{Name: "glibc"}},
+		{"python>=3.11", Depend{Name: "python", Op: GreaterEqual, Version: "3.11"}},
+		{"python<3.12", Depend{Name: "python", Op: Less, Version: "3.12"}},
+		{"foo<=1:2.0-1", Depend{Name: "foo", Op: LessEqual, Version: "1:2.0-1"}},
+		{"foo>2", Depend{Name: "foo", Op: Greater, Version: "2"}},
+		{"libalpm.so=15-64", Depend{Name: "libalpm.so", Op: Equal, Version: "15-64"}},
+		{"python-bar: for bar support", Depend{Name: "python-bar", Description: "for bar support"}},
+		{"python-bar>=2: for bar: and more", Depend{Name: "python-bar", Op: GreaterEqual, Version: "2", Description: "for bar: and more"}},
+	}
+	for _, tt := range tests {
+		got := ParseDepend(tt.in)
+		if got != tt.want {
+			t.Errorf("ParseDepend(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+		if s := got.String(); s != tt.in {
+			t.Errorf("ParseDepend(%q).String() = %q", tt.in, s)
+		}
+	}
+
+	if !ParseDepend("libalpm.so").IsLibrary() || !ParseDepend("libfoo.so.1").IsLibrary() {
+		t.Errorf("expected library dependencies")
+	}
+	if ParseDepend("sonar").IsLibrary() {
+		t.Errorf("expected package dependency")
+	}
+}
+
+func TestDependSatisfiedBy(t *testing.T) {
+	tests := []struct {
+		dep      string
+		name     string
+		version  string
+		provides []string
+		want     bool
+	}{
+		{"foo", "foo", "1.0-1", nil, true},
+		{"foo", "bar", "1.0-1", nil, false},
+		{"foo>=1.0", "foo", "1.0-1", nil, true},
+		{"foo>1.0", "foo", "1.0-1", nil, false},
+		{"foo>1.0-1", "foo", "1.0-2", nil, true},
+		{"foo<2", "foo", "1:1.0-1", nil, false},
+		{"foo=1.0", "foo", "1.0-3", nil, true},
+		{"foo", "foo-git", "r10.abc-1", []string{"foo"}, true},
+		{"foo>=1.0", "foo-git", "r10.abc-1", []string{"foo"}, false},
+		{"foo>=1.0", "foo-git", "r10.abc-1", []string{"foo=1.2"}, true},
+		{"foo>=1.3", "foo-git", "r10.abc-1", []string{"foo=1.2"}, false},
+		{"libalpm.so", "pacman", "6.1.0-3", []string{"libalpm.so=15-64"}, true},
+		{"libalpm.so=15-64", "pacman", "6.1.0-3", []string{"libalpm.so=15-64"}, true},
+		{"libalpm.so=14-64", "pacman", "6.1.0-3", []string{"libalpm.so=15-64"}, false},
+	}
+	for _, tt := range tests {
+		got := ParseDepend(tt.dep).SatisfiedBy(tt.name, tt.version, tt.provides)
+		if got != tt.want {
+			t.Errorf("%q satisfied by %s %s %v = %v, want %v", tt.dep, tt.name, tt.version, tt.provides, got, tt.want)
+		}
+	}
+}
@@ -95,8 +95,8 @@ func parseIntoEVR(a string) (e int, v string, r int) {
 	}
 
 	// Find out our r
-	r, t = -1, len(a)-1
-	for i := t; i >= 0; i-- {
+	r, t = -1, len(a)
+	for i := t - 1; i >= 0; i-- {
 		if !isdigit(a[i]) {
 			if a[i] == '-' {
 				r, _ = strconv.Atoi(a[i+1:])
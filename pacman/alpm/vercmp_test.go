@@ -66,3 +66,22 @@ func cmp2str(c int) rune {
 	}
 	return '='
 }
+
+func TestVerCmpWithoutRelease(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2", "1.3", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0-1", 0},
+		{"1.0-1", "1.0", 0},
+		{"2", "10", -1},
+		{"1:1.0", "2.0", 1},
+	}
+	for _, tt := range tests {
+		if c := VerCmp(tt.a, tt.b); c != tt.want {
+			t.Errorf("VerCmp: expected %s %c %s; got %s %c %s", tt.a, cmp2str(tt.want), tt.b, tt.a, cmp2str(c), tt.b)
+		}
+	}
+}
@@ -7,10 +7,8 @@ package aur
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/cassava/repoctl/pacman"
@@ -76,10 +74,10 @@ func (e NotFoundError) Error() string {
 	return buf.String()
 }
 
-// IsNotFound returns true if the error is an instance of NotFoundError.
+// IsNotFound returns true if the error is or wraps a NotFoundError.
 func IsNotFound(err error) bool {
-	_, ok := err.(*NotFoundError)
-	return ok
+	var nfe *NotFoundError
+	return errors.As(err, &nfe)
 }
 
 // response is what the AUR api returns:
@@ -118,6 +116,7 @@ type response struct {
 	Type        string     `json:"type"`
 	ResultCount int        `json:"resultcount"`
 	Results     []*Package `json:"results"`
+	Error       string     `json:"error"`
 }
 
 // Package is the information that we can retrieve about a package that is
@@ -155,25 +154,36 @@ type Package struct {
 	Popularity     float64
 	OutOfDate      int
 	Maintainer     string
+	Submitter      string
 	FirstSubmitted uint64
 	LastModified   uint64
 	URLPath        string
 	Groups         []string
 	Depends        []string
 	MakeDepends    []string
+	CheckDepends   []string
 	OptDepends     []string
 	Conflicts      []string
 	Provides       []string
 	Replaces       []string
 	License        []string
 	Keywords       []string
+
+	// baseURL is the URL of the AUR that the package was read from.
+	baseURL string
+	// stale is true if the package was read from a cache entry that
+	// is older than the TTL of the cache.
+	stale bool
 }
 
+// Stale returns true if the information about the package was read from
+// the cache and might be out of date, because the AUR was not asked again.
+func (p *Package) Stale() bool { return p.stale }
+
 // Pkg converts an aur.Package into a pacman.Package.
 //
-// Note that only a few fields in the resulting Package are actually filled in,
-// namely Origin, Name, Version, Description, URL, and License. This is all the
-// information that we are able to retrieve.
+// Note that only the fields in the resulting Package that AUR knows about are
+// filled in. In particular, there is no filename, size, or build date.
 func (p *Package) Pkg() *pacman.Package {
 	return &pacman.Package{
 		Origin:      pacman.AUROrigin,
@@ -182,12 +192,16 @@ func (p *Package) Pkg() *pacman.Package {
 		Version:     p.Version,
 		Description: p.Description,
 		URL:         p.URL,
-		// TODO: License is string, but p.License is []string
-		// Has there been a format change?
-		//License:     p.License,
+		License:     p.License,
+		Groups:      p.Groups,
+		Replaces:    p.Replaces,
+		Provides:    p.Provides,
+		Conflicts:   p.Conflicts,
 		Depends:     p.Depends,
 		MakeDepends: p.MakeDepends,
-		// TODO: Update this here
+
+		CheckDepends:    p.CheckDepends,
+		OptionalDepends: p.OptDepends,
 	}
 }
 
@@ -203,13 +217,18 @@ func (p *Package) PkgDepends() []string { return p.Depends }
 // PkgMakeDepends returns the make dependenciess of the package.
 func (p *Package) PkgMakeDepends() []string { return p.MakeDepends }
 
-// DownloadURL returns the URL for downloading the PKGBUILD tarball.
+// DownloadURL returns the URL for downloading the PKGBUILD tarball,
+// from the AUR that the package was read from.
 func (p *Package) DownloadURL() string {
 	urlPath := p.URLPath
 	if p.PackageBase != p.Name {
 		urlPath = p.downloadURLWithName(p.PackageBase)
 	}
-	return fmt.Sprintf("https://aur.archlinux.org%s", urlPath)
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return baseURL + urlPath
 }
 
 func (p *Package) downloadURLWithName(baseName string) string {
@@ -227,135 +246,47 @@ func (p *Package) downloadURLWithName(baseName string) string {
 	return p.URLPath[:fromIdx+1] + baseName + filename[toIdx:]
 }
 
-const (
-	searchURL    = "https://aur.archlinux.org/rpc?v=5&type=search&by=name&arg=%s"
-	multiInfoURL = "https://aur.archlinux.org/rpc?v=5&type=multiinfo&arg[]=%s"
-	multiInfoArg = "&arg[]="
-)
-
-// generateMultiInfoURL creates a URL that gets the package information from AUR.
-func generateMultiInfoURL(args []string) string {
-	na := make([]string, len(args))
-	for i, s := range args {
-		na[i] = url.QueryEscape(s)
-	}
-	return fmt.Sprintf(multiInfoURL, strings.Join(na, multiInfoArg))
+// Search returns the packages on AUR for which the field by matches the
+// query, using DefaultClient. See SearchFields for the supported fields.
+func Search(by, query string) (Packages, error) {
+	return DefaultClient.Search(DefaultContext, by, query)
 }
 
+// SearchByName returns the packages on AUR whose name contains query,
+// using DefaultClient.
 func SearchByName(query string) (Packages, error) {
-	q := fmt.Sprintf(searchURL, url.QueryEscape(query))
-	resp, err := http.Get(q)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return DefaultClient.SearchByName(DefaultContext, query)
+}
 
-	var msg response
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&msg)
-	return msg.Results, err
+// SearchByProvides returns the packages on AUR that provide the given name,
+// using DefaultClient.
+//
+// Note that search results only contain basic information about the
+// packages, and in particular no dependencies or provides; use ReadAll
+// to get those.
+func SearchByProvides(name string) (Packages, error) {
+	return DefaultClient.SearchByProvides(DefaultContext, name)
 }
 
 // Read reads package information from the Arch Linux User Repository (AUR)
-// online.
+// online, using DefaultClient.
 //
 // If a package cannot be found, (nil, *NotFoundError) is returned.
 func Read(pkgname string) (*Package, error) {
-	q := generateMultiInfoURL([]string{pkgname})
-	resp, err := http.Get(q)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return DefaultClient.Read(DefaultContext, pkgname)
+}
 
-	var msg response
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&msg)
-	if err != nil {
-		return nil, err
-	}
-	if msg.ResultCount == 0 {
-		return nil, &NotFoundError{Names: []string{pkgname}}
-	}
-	return msg.Results[0], nil
+// Names returns the names of all packages on AUR from the dump of
+// DefaultClient. If it has no dump, ErrNoDump is returned.
+func Names() ([]string, error) {
+	return DefaultClient.Names(DefaultContext)
 }
 
 // ReadAll reads multiple packages from the Arch Linux User Repository (AUR)
-// at once.
+// at once, using DefaultClient.
 //
 // If any packages cannot be found, (Packages, *NotFoundError) is returned.
 // That is, all successfully read packages are returned.
 func ReadAll(pkgnames []string) (Packages, error) {
-	// We only query at most 200 packages at a time, the limit currently
-	// appears to be 250, but we'll stay well beneath that for now.
-	const limit = 200
-	if len(pkgnames) <= limit {
-		return readAll(pkgnames)
-	}
-
-	var pkgs Packages
-	err := &NotFoundError{
-		Names: make([]string, 0, 0),
-	}
-	for len(pkgnames) > 0 {
-		// Select next slice of messages
-		var slice []string
-		if len(pkgnames) > limit {
-			slice = pkgnames[:limit]
-			pkgnames = pkgnames[limit:]
-		} else {
-			slice = pkgnames
-			pkgnames = []string{}
-		}
-
-		// Query selected slice of messages
-		p, e := readAll(slice)
-		if e != nil {
-			nfe, ok := e.(*NotFoundError)
-			if !ok {
-				// e is not NotFoundError, and we don't know how to handle it,
-				// so return directly as-is.
-				return nil, e
-			}
-			err.Names = append(err.Names, nfe.Names...)
-		}
-		pkgs = append(pkgs, p...)
-	}
-	if len(err.Names) == 0 {
-		return pkgs, nil
-	} else {
-		return pkgs, err
-	}
-}
-
-func readAll(pkgnames []string) (Packages, error) {
-	q := generateMultiInfoURL(pkgnames)
-	resp, err := http.Get(q)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var msg response
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&msg)
-	if err != nil {
-		return nil, err
-	}
-	if msg.ResultCount != len(pkgnames) {
-		m := make(map[string]bool)
-		nfe := &NotFoundError{
-			Names: make([]string, 0, len(pkgnames)-msg.ResultCount),
-		}
-		for _, i := range msg.Results {
-			m[i.Name] = true
-		}
-		for _, s := range pkgnames {
-			if !m[s] {
-				nfe.Names = append(nfe.Names, s)
-			}
-		}
-		return msg.Results, nfe
-	}
-	return msg.Results, nil
+	return DefaultClient.ReadAll(DefaultContext, pkgnames)
 }
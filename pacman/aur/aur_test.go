@@ -2,9 +2,20 @@
 // Use of this source code is governed by a MIT-style
 // license that can be found in the LICENSE file.
 
-package aur
+package aur_test
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/cassava/repoctl/pacman/aur/aurtest"
+)
+
+// server stands in for the AUR, so that the tests can run offline.
+var server *aurtest.Server
 
 var (
 	exists    = []string{"repoctl", "fairsplit", "moped"}
@@ -112,9 +123,30 @@ var (
 	}
 )
 
+func TestMain(m *testing.M) {
+	server = aurtest.NewServer(
+		&aur.Package{Name: "repoctl", Version: "0.21-1", Maintainer: "cassava"},
+		&aur.Package{Name: "fairsplit", Version: "1.0-1"},
+		&aur.Package{Name: "moped", Version: "0.4-1"},
+		&aur.Package{Name: "transgui-qt", PackageBase: "transgui", Version: "5.18.0-1"},
+	)
+	// Only some of the many packages are on AUR, the others are in the
+	// official repositories.
+	for i, n := range many {
+		if i%3 == 0 {
+			server.Add(&aur.Package{Name: n, Version: "1.0-1"})
+		}
+	}
+	aur.DefaultClient = server.Client()
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
 func TestRead1(z *testing.T) {
 	for _, n := range exists {
-		i, err := Read(n)
+		i, err := aur.Read(n)
 		if err != nil {
 			z.Errorf("unexpected error: %s", err)
 		}
@@ -128,13 +160,13 @@ func TestRead1(z *testing.T) {
 
 func TestRead2(z *testing.T) {
 	for _, n := range notExists {
-		i, err := Read(n)
+		i, err := aur.Read(n)
 		if i != nil {
 			z.Errorf("expecting i to be nil")
 		}
 		if err == nil {
 			z.Errorf("expecting error, got nil")
-		} else if nf, ok := err.(*NotFoundError); ok {
+		} else if nf, ok := err.(*aur.NotFoundError); ok {
 			if len(nf.Names) != 1 {
 				z.Errorf("wrong number of names returned")
 			} else if nf.Names[0] != n {
@@ -147,7 +179,7 @@ func TestRead2(z *testing.T) {
 }
 
 func TestReadAll1(z *testing.T) {
-	is, err := ReadAll(exists)
+	is, err := aur.ReadAll(exists)
 	if err != nil {
 		z.Errorf("unexpected error: %s", err)
 	}
@@ -157,13 +189,13 @@ func TestReadAll1(z *testing.T) {
 }
 
 func TestReadAll2(z *testing.T) {
-	is, err := ReadAll(notExists)
+	is, err := aur.ReadAll(notExists)
 	if len(is) != 0 {
 		z.Errorf("expecting is to have zero elements")
 	}
 	if err == nil {
 		z.Errorf("expecting error, got nil")
-	} else if nf, ok := err.(*NotFoundError); ok {
+	} else if nf, ok := err.(*aur.NotFoundError); ok {
 		if len(nf.Names) != len(notExists) {
 			z.Errorf("wrong number of names returned")
 		} else {
@@ -179,13 +211,13 @@ func TestReadAll2(z *testing.T) {
 }
 
 func TestReadMany(z *testing.T) {
-	is, err := ReadAll(many)
+	is, err := aur.ReadAll(many)
 	if len(is) == 0 {
 		z.Errorf("expecting to have more than zero elements")
 	}
 	if err == nil {
 		z.Errorf("expecting error, got nil")
-	} else if nf, ok := err.(*NotFoundError); ok {
+	} else if nf, ok := err.(*aur.NotFoundError); ok {
 	next_package:
 		for _, n := range many {
 			// Either the package was found:
@@ -211,23 +243,38 @@ func TestReadMany(z *testing.T) {
 }
 
 func TestDownloadURL(z *testing.T) {
-	i, err := Read("repoctl")
+	i, err := aur.Read("repoctl")
 	if err != nil {
 		z.Errorf("unexpected error: %s", err)
 		z.FailNow()
 	}
-	if i.DownloadURL() != "https://aur.archlinux.org/cgit/aur.git/snapshot/repoctl.tar.gz" {
+	if i.DownloadURL() != server.URL+"/cgit/aur.git/snapshot/repoctl.tar.gz" {
 		z.Errorf("download url incorrect: %s", i.DownloadURL())
 	}
 }
 
 func TestDownloadURLWithBaseName(z *testing.T) {
-	i, err := Read("transgui-qt")
+	i, err := aur.Read("transgui-qt")
 	if err != nil {
 		z.Errorf("unexpected error: %s", err)
 		z.FailNow()
 	}
-	if i.DownloadURL() != "https://aur.archlinux.org/cgit/aur.git/snapshot/transgui.tar.gz" {
+	if i.DownloadURL() != server.URL+"/cgit/aur.git/snapshot/transgui.tar.gz" {
 		z.Errorf("download url incorrect: %s", i.DownloadURL())
 	}
 }
+
+func TestIsNotFound(z *testing.T) {
+	_, err := aur.ReadAll([]string{"repoctl", notExists[0]})
+	if !aur.IsNotFound(err) {
+		z.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	wrapped := fmt.Errorf("cannot read packages: %w", err)
+	var nfe *aur.NotFoundError
+	if !aur.IsNotFound(wrapped) || !errors.As(wrapped, &nfe) || nfe.Names[0] != notExists[0] {
+		z.Errorf("expected wrapped *NotFoundError, got %v", wrapped)
+	}
+	if aur.IsNotFound(errors.New("other")) || aur.IsNotFound(nil) {
+		z.Errorf("expected other errors not to be *NotFoundError")
+	}
+}
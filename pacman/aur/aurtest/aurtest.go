@@ -0,0 +1,373 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package aurtest provides a stand-in for the AUR for testing.
+//
+// The server answers the same RPC requests as the AUR does, from the
+// packages that it is given, and serves snapshot tarballs of them as well
+// as the dumps of all package names and metadata:
+//
+//	srv := aurtest.NewServer(&aur.Package{Name: "foo", Version: "1.0-1"})
+//	defer srv.Close()
+//	pkg, err := srv.Client().Read(ctx, "foo")
+package aurtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cassava/repoctl/pacman/alpm"
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+// SnapshotPath is the path under which snapshots are served.
+const SnapshotPath = "/cgit/aur.git/snapshot/"
+
+// Server is an httptest.Server that stands in for the AUR.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	pkgs      map[string]*aur.Package
+	snapshots map[string][]byte
+	failures  int
+	failCode  int
+	requests  int
+	userAgent string
+	modified  time.Time
+	version   int
+}
+
+// NewServer starts and returns a new server with the given packages.
+// The caller should call Close when finished, to shut it down.
+func NewServer(pkgs ...*aur.Package) *Server {
+	s := &Server{
+		pkgs:      make(map[string]*aur.Package),
+		snapshots: make(map[string][]byte),
+	}
+	s.Add(pkgs...)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a client for the server, which does not wait long
+// before retrying requests.
+func (s *Server) Client() *aur.Client {
+	c := aur.NewClient(s.URL)
+	c.HTTPClient = s.Server.Client()
+	c.RetryWait = time.Millisecond
+	return c
+}
+
+// Add adds packages to the server, replacing any packages with the same
+// name. If the package base or the URL path of a package are empty,
+// they are filled in as the AUR would.
+func (s *Server) Add(pkgs ...*aur.Package) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range pkgs {
+		if p.PackageBase == "" {
+			p.PackageBase = p.Name
+		}
+		if p.URLPath == "" {
+			p.URLPath = SnapshotPath + p.PackageBase + ".tar.gz"
+		}
+		s.pkgs[p.Name] = p
+	}
+	s.modified = time.Now().UTC().Truncate(time.Second)
+	s.version++
+}
+
+// AddSnapshot sets the snapshot of the package base to a tarball with the
+// given files, which map paths relative to the package base directory to
+// their contents. Packages without a snapshot get one that contains only
+// a minimal PKGBUILD.
+func (s *Server) AddSnapshot(base string, files map[string]string) error {
+	bs, err := makeSnapshot(base, files)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.snapshots[base] = bs
+	s.mu.Unlock()
+	return nil
+}
+
+// FailNext makes the server respond to the next n requests with the
+// HTTP status code.
+func (s *Server) FailNext(n, code int) {
+	s.mu.Lock()
+	s.failures, s.failCode = n, code
+	s.mu.Unlock()
+}
+
+// Requests returns the number of requests that the server has received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// UserAgent returns the User-Agent of the last request.
+func (s *Server) UserAgent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userAgent
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.userAgent = r.UserAgent()
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, http.StatusText(s.failCode), s.failCode)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/rpc" || r.URL.Path == "/rpc/":
+		s.serveRPC(w, r)
+	case strings.HasPrefix(r.URL.Path, SnapshotPath):
+		s.serveSnapshot(w, r)
+	case r.URL.Path == aur.NamesDumpPath || r.URL.Path == aur.MetaDumpPath:
+		s.serveDump(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// response is what the AUR RPC interface returns.
+type response struct {
+	Version     int            `json:"version"`
+	Type        string         `json:"type"`
+	ResultCount int            `json:"resultcount"`
+	Results     []*aur.Package `json:"results"`
+	Error       string         `json:"error,omitempty"`
+}
+
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	resp := response{Version: 5, Type: q.Get("type"), Results: []*aur.Package{}}
+	switch resp.Type {
+	case "multiinfo", "info":
+		for _, name := range q["arg[]"] {
+			if p, ok := s.pkgs[name]; ok {
+				resp.Results = append(resp.Results, p)
+			}
+		}
+	case "search":
+		by := q.Get("by")
+		if by == "" {
+			by = "name-desc"
+		}
+		arg := q.Get("arg")
+		if match := searchFields[by]; match == nil {
+			resp.Type, resp.Error = "error", "Incorrect by field specified."
+		} else if arg == "" {
+			resp.Type, resp.Error = "error", "Query arg too small."
+		} else {
+			for _, p := range s.sortedPackages() {
+				if match(p, arg) {
+					resp.Results = append(resp.Results, searchResult(p))
+				}
+			}
+		}
+	default:
+		resp.Type, resp.Error = "error", "Incorrect request type specified."
+	}
+	resp.ResultCount = len(resp.Results)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) sortedPackages() []*aur.Package {
+	pkgs := make([]*aur.Package, 0, len(s.pkgs))
+	for _, p := range s.pkgs {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs
+}
+
+// searchResult returns a copy of p with only the fields that the AUR
+// includes in search results.
+func searchResult(p *aur.Package) *aur.Package {
+	return &aur.Package{
+		ID:             p.ID,
+		Name:           p.Name,
+		PackageBaseID:  p.PackageBaseID,
+		PackageBase:    p.PackageBase,
+		Version:        p.Version,
+		Description:    p.Description,
+		URL:            p.URL,
+		NumVotes:       p.NumVotes,
+		Popularity:     p.Popularity,
+		OutOfDate:      p.OutOfDate,
+		Maintainer:     p.Maintainer,
+		Submitter:      p.Submitter,
+		FirstSubmitted: p.FirstSubmitted,
+		LastModified:   p.LastModified,
+		URLPath:        p.URLPath,
+	}
+}
+
+// searchFields maps the fields that can be searched by to functions
+// that match a package against the query, as the AUR does.
+var searchFields = map[string]func(p *aur.Package, arg string) bool{
+	"name": func(p *aur.Package, arg string) bool {
+		return strings.Contains(p.Name, arg)
+	},
+	"name-desc": func(p *aur.Package, arg string) bool {
+		return strings.Contains(p.Name, arg) || strings.Contains(p.Description, arg)
+	},
+	"maintainer":   func(p *aur.Package, arg string) bool { return p.Maintainer == arg },
+	"submitter":    func(p *aur.Package, arg string) bool { return p.Submitter == arg },
+	"depends":      func(p *aur.Package, arg string) bool { return hasName(p.Depends, arg) },
+	"makedepends":  func(p *aur.Package, arg string) bool { return hasName(p.MakeDepends, arg) },
+	"optdepends":   func(p *aur.Package, arg string) bool { return hasName(p.OptDepends, arg) },
+	"checkdepends": func(p *aur.Package, arg string) bool { return hasName(p.CheckDepends, arg) },
+	"provides": func(p *aur.Package, arg string) bool {
+		return p.Name == arg || hasName(p.Provides, arg)
+	},
+	"conflicts": func(p *aur.Package, arg string) bool { return hasName(p.Conflicts, arg) },
+	"replaces":  func(p *aur.Package, arg string) bool { return hasName(p.Replaces, arg) },
+	"groups":    func(p *aur.Package, arg string) bool { return hasString(p.Groups, arg) },
+	"keywords":  func(p *aur.Package, arg string) bool { return hasString(p.Keywords, arg) },
+}
+
+// hasName returns whether any of the dependencies in xs has the name arg.
+// Optional dependencies may have a description, which is ignored.
+func hasName(xs []string, arg string) bool {
+	for _, x := range xs {
+		if i := strings.Index(x, ":"); i != -1 {
+			x = x[:i]
+		}
+		if alpm.ParseDepend(strings.TrimSpace(x)).Name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(xs []string, arg string) bool {
+	for _, x := range xs {
+		if x == arg {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	base := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, SnapshotPath), ".tar.gz")
+	bs, ok := s.snapshots[base]
+	if !ok {
+		var p *aur.Package
+		for _, x := range s.sortedPackages() {
+			if x.PackageBase == base {
+				p = x
+				break
+			}
+		}
+		if p == nil {
+			http.NotFound(w, r)
+			return
+		}
+		pkgver, pkgrel := p.Version, "1"
+		if i := strings.LastIndex(pkgver, "-"); i != -1 {
+			pkgver, pkgrel = pkgver[:i], pkgver[i+1:]
+		}
+		pkgbuild := fmt.Sprintf("pkgname=%s\npkgver=%s\npkgrel=%s\narch=(any)\n", p.Name, pkgver, pkgrel)
+		var err error
+		bs, err = makeSnapshot(base, map[string]string{"PKGBUILD": pkgbuild})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-gzip")
+	w.Write(bs)
+}
+
+// serveDump serves the list of package names or the metadata of all
+// packages. Conditional requests are supported, with the ETag changing
+// whenever packages are added.
+func (s *Server) serveDump(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	pkgs := s.sortedPackages()
+	if r.URL.Path == aur.NamesDumpPath {
+		fmt.Fprintf(gw, "# AUR package list, generated on %s\n", s.modified.Format(time.RFC1123))
+		for _, p := range pkgs {
+			fmt.Fprintln(gw, p.Name)
+		}
+	} else if err := json.NewEncoder(gw).Encode(pkgs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := gw.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, s.version))
+	http.ServeContent(w, r, r.URL.Path, s.modified, bytes.NewReader(buf.Bytes()))
+}
+
+// makeSnapshot returns a gzip compressed tarball with the files in the
+// directory base, as the AUR creates them.
+func makeSnapshot(base string, files map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     base + "/",
+		Mode:     0755,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     base + "/" + name,
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached responses are used by default
+// before the AUR is asked again. It is short, so that new versions on
+// the AUR are noticed soon.
+const DefaultCacheTTL = 5 * time.Minute
+
+// Cache stores the responses of the AUR on disk, so that the same
+// packages need not be requested again and again. Every package that is
+// read, or that could not be found, and every search is stored in its
+// own file in Dir.
+type Cache struct {
+	// Dir is the directory that the cache is stored in.
+	// It is created if it does not exist.
+	Dir string
+	// TTL is how long entries are fresh. Entries that are older than that
+	// are only used when the AUR cannot be reached, or in offline mode.
+	TTL time.Duration
+}
+
+// NewCache returns a new cache in dir. If ttl is zero, DefaultCacheTTL
+// is used.
+func NewCache(dir string, ttl time.Duration) *Cache {
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Cache{Dir: dir, TTL: ttl}
+}
+
+// cacheEntry is what is stored in a cache file. For a package that could
+// not be found, Results is empty.
+type cacheEntry struct {
+	Time    time.Time  `json:"time"`
+	Results []*Package `json:"results"`
+}
+
+// fresh returns whether the entry is younger than the TTL of the cache.
+func (c *Cache) fresh(e *cacheEntry) bool {
+	return time.Since(e.Time) < c.TTL
+}
+
+// path returns the file that the entry for key is stored in. The key is
+// hashed together with the URL of the AUR, because different profiles
+// may use different AURs, and because queries may contain any character.
+func (c *Cache) path(baseURL, kind, key string) string {
+	sum := sha256.Sum256([]byte(baseURL + "\x00" + kind + "\x00" + key))
+	return filepath.Join(c.Dir, kind+"-"+hex.EncodeToString(sum[:16])+".json")
+}
+
+// get returns the entry for key, or nil if there is none. The packages
+// are marked stale if the entry is not fresh.
+func (c *Cache) get(baseURL, kind, key string) *cacheEntry {
+	bs, err := os.ReadFile(c.path(baseURL, kind, key))
+	if err != nil {
+		return nil
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(bs, &e); err != nil {
+		return nil
+	}
+	stale := !c.fresh(&e)
+	for _, p := range e.Results {
+		p.baseURL = baseURL
+		p.stale = stale
+	}
+	return &e
+}
+
+// put stores the results for key. Errors are ignored, since the cache
+// is only an optimization.
+func (c *Cache) put(baseURL, kind, key string, results []*Package) {
+	if results == nil {
+		results = []*Package{}
+	}
+	bs, err := json.Marshal(&cacheEntry{Time: time.Now(), Results: results})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file first, so that nobody reads a partial entry.
+	f, err := os.CreateTemp(c.Dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(bs)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(baseURL, kind, key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
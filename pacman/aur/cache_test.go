@@ -0,0 +1,175 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/cassava/repoctl/pacman/aur/aurtest"
+)
+
+func newCachedClient(z *testing.T, ttl time.Duration) (*aurtest.Server, *aur.Client) {
+	srv := aurtest.NewServer(
+		&aur.Package{Name: "foo", Version: "1.0-1"},
+		&aur.Package{Name: "bar", Version: "2.0-1"},
+	)
+	c := srv.Client()
+	c.Cache = aur.NewCache(z.TempDir(), ttl)
+	c.MaxRetries = 0
+	return srv, c
+}
+
+func TestCacheFresh(z *testing.T) {
+	srv, c := newCachedClient(z, time.Hour)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := c.ReadAll(ctx, []string{"foo", "bar", "baz"}); !aur.IsNotFound(err) {
+		z.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	if n := srv.Requests(); n != 1 {
+		z.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// Everything is in the cache, including that baz does not exist.
+	pkgs, err := c.ReadAll(ctx, []string{"foo", "bar", "baz"})
+	if !aur.IsNotFound(err) {
+		z.Fatalf("expected *NotFoundError, got %v", err)
+	}
+	if len(pkgs) != 2 {
+		z.Errorf("expected 2 packages, got %d", len(pkgs))
+	}
+	for _, p := range pkgs {
+		if p.Stale() {
+			z.Errorf("expected %s not to be stale", p.Name)
+		}
+	}
+	if n := srv.Requests(); n != 1 {
+		z.Errorf("expected no more requests, got %d", n-1)
+	}
+	if p, _ := c.Read(ctx, "foo"); p == nil || p.DownloadURL() != srv.URL+"/cgit/aur.git/snapshot/foo.tar.gz" {
+		z.Errorf("wrong package from cache: %v", p)
+	}
+
+	// Only the packages that are not cached are requested.
+	srv.Add(&aur.Package{Name: "qux", Version: "1.0-1"})
+	if _, err := c.ReadAll(ctx, []string{"foo", "qux"}); err != nil {
+		z.Errorf("unexpected error: %s", err)
+	}
+	if n := srv.Requests(); n != 2 {
+		z.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestCacheStale(z *testing.T) {
+	srv, c := newCachedClient(z, time.Nanosecond)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := c.ReadAll(ctx, []string{"foo", "bar"}); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// Stale entries are not used if the AUR can be reached.
+	srv.Add(&aur.Package{Name: "foo", Version: "1.1-1"})
+	p, err := c.Read(ctx, "foo")
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if p.Version != "1.1-1" || p.Stale() {
+		z.Errorf("expected fresh foo 1.1-1, got %s (stale: %v)", p.Version, p.Stale())
+	}
+	time.Sleep(time.Millisecond)
+
+	// But they are if it can't.
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	pkgs, err := c.ReadAll(ctx, []string{"foo", "bar"})
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	for _, p := range pkgs {
+		if !p.Stale() {
+			z.Errorf("expected %s to be stale", p.Name)
+		}
+	}
+
+	// Unless they are not all there.
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	if _, err := c.ReadAll(ctx, []string{"foo", "qux"}); err == nil || aur.IsNotFound(err) {
+		z.Errorf("expected error, got %v", err)
+	}
+}
+
+func TestCacheStaleNoRetry(z *testing.T) {
+	srv, c := newCachedClient(z, time.Nanosecond)
+	defer srv.Close()
+	ctx := context.Background()
+	c.MaxRetries = 3
+	c.RetryWait = time.Millisecond
+
+	if _, err := c.Read(ctx, "foo"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.SearchByName(ctx, "ba"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// With stale entries to fall back to, failed requests are not retried.
+	srv.FailNext(2, http.StatusServiceUnavailable)
+	before := srv.Requests()
+	if p, err := c.Read(ctx, "foo"); err != nil || !p.Stale() {
+		z.Errorf("expected stale foo, got %v, %v", p, err)
+	}
+	if pkgs, err := c.SearchByName(ctx, "ba"); err != nil || len(pkgs) != 1 || !pkgs[0].Stale() {
+		z.Errorf("expected stale search result bar, got %v, %v", pkgs, err)
+	}
+	if n := srv.Requests() - before; n != 2 {
+		z.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestCacheOffline(z *testing.T) {
+	srv, c := newCachedClient(z, time.Nanosecond)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := c.Read(ctx, "foo"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.SearchByName(ctx, "ba"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	c.Offline = true
+	before := srv.Requests()
+	p, err := c.Read(ctx, "foo")
+	if err != nil || !p.Stale() {
+		z.Errorf("expected stale foo, got %v, %v", p, err)
+	}
+	if _, err := c.ReadAll(ctx, []string{"foo", "bar"}); !errors.Is(err, aur.ErrNotCached) {
+		z.Errorf("expected ErrNotCached for bar, got %v", err)
+	}
+	pkgs, err := c.SearchByName(ctx, "ba")
+	if err != nil || len(pkgs) != 1 || pkgs[0].Name != "bar" {
+		z.Errorf("expected cached search result bar, got %v, %v", pkgs, err)
+	}
+	if _, err := c.SearchByName(ctx, "fo"); !errors.Is(err, aur.ErrNotCached) {
+		z.Errorf("expected ErrNotCached for search, got %v", err)
+	}
+	if _, err := c.Snapshot(ctx, &aur.Package{Name: "foo"}); err != aur.ErrOffline {
+		z.Errorf("expected ErrOffline, got %v", err)
+	}
+	if n := srv.Requests() - before; n != 0 {
+		z.Errorf("expected no requests, got %d", n)
+	}
+}
@@ -0,0 +1,582 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cassava/repoctl/pacman/alpm"
+)
+
+const (
+	// DefaultBaseURL is the URL of the official AUR.
+	DefaultBaseURL = "https://aur.archlinux.org"
+	// DefaultUserAgent is the User-Agent that a Client sends by default.
+	DefaultUserAgent = "repoctl (+https://github.com/cassava/repoctl)"
+)
+
+// DefaultClient is the client used by the functions of this package,
+// such as Read and ReadAll. It can be replaced, for example to use
+// a mirror of AUR.
+var DefaultClient = NewClient("")
+
+// DefaultContext is the context of the requests that the functions of this
+// package make with DefaultClient. Programs can replace it with a context
+// that is cancelled when the user interrupts them.
+var DefaultContext = context.Background()
+
+// Client queries an AUR through its RPC interface and downloads
+// snapshots of package sources from it.
+//
+// Requests that fail with 429 Too Many Requests or a 5xx status code,
+// or that fail because of network errors, are retried with exponential
+// backoff, unless the context is cancelled.
+//
+// If the client has a cache, packages and searches are read from the cache
+// while they are fresh. When the AUR cannot be reached, or in offline mode,
+// stale entries are used as well; see Package.Stale. Requests for which a
+// stale entry exists are not retried and time out after StaleTimeout.
+//
+// If the client has a dump, packages are read and searched in the dump
+// instead, and the RPC interface is not used at all.
+type Client struct {
+	// BaseURL is the URL of the AUR, without trailing slash,
+	// such as "https://aur.archlinux.org".
+	BaseURL string
+	// HTTPClient is used for all requests.
+	HTTPClient *http.Client
+	// UserAgent is sent with every request.
+	UserAgent string
+	// MaxRetries is the number of times a request is retried.
+	MaxRetries int
+	// RetryWait is how long to wait before the first retry. It doubles
+	// with every retry, unless the server says how long to wait.
+	RetryWait time.Duration
+	// MaxRetryWait is the longest that the client waits before a retry,
+	// even if the server asks for more with Retry-After. If it is zero,
+	// there is no limit.
+	MaxRetryWait time.Duration
+	// StaleTimeout is the timeout for requests for which a stale entry in
+	// the cache can be used instead. It is only used if it is shorter than
+	// the timeout of HTTPClient.
+	StaleTimeout time.Duration
+	// Cache stores the responses of the AUR. If nil, nothing is cached.
+	Cache *Cache
+	// Dump is the local copy of all packages on the AUR. If nil, the
+	// RPC interface is used instead.
+	Dump *Dump
+	// Offline causes the AUR not to be asked at all; only the cache is
+	// used. Reading packages or searches that are not in the cache fails
+	// with ErrNotCached.
+	Offline bool
+}
+
+// NewClient returns a new client for the AUR at baseURL, with a timeout
+// of 30 seconds for each request, or 5 seconds if a stale entry in the cache
+// can be used instead, and which waits at most a minute before retrying.
+// If baseURL is empty, DefaultBaseURL is used.
+func NewClient(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		UserAgent:    DefaultUserAgent,
+		MaxRetries:   3,
+		RetryWait:    time.Second,
+		MaxRetryWait: time.Minute,
+		StaleTimeout: 5 * time.Second,
+	}
+}
+
+// ErrOffline is returned when something needs to be downloaded from the
+// AUR in offline mode, such as a snapshot.
+var ErrOffline = errors.New("cannot download from AUR in offline mode")
+
+// ErrNotCached is returned in offline mode when packages or searches are
+// not in the cache.
+var ErrNotCached = errors.New("not in AUR cache in offline mode")
+
+// StatusError is returned when the AUR responds with an unexpected
+// HTTP status code, after any retries.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Get requests url from the AUR and returns the response if the status
+// is 200 OK. The caller must close the body of the response.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.get(ctx, url, nil)
+}
+
+// get requests url with the additional header. Since the header may make
+// the request conditional, the response is also returned if the status
+// is 304 Not Modified.
+func (c *Client) get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		if c.UserAgent != "" {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		retry := false
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			retry = true
+		} else if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		} else {
+			resp.Body.Close()
+			err = &StatusError{URL: url, StatusCode: resp.StatusCode}
+			retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		}
+		if !retry || attempt >= c.MaxRetries {
+			return nil, err
+		}
+
+		delay := wait
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = d
+			}
+		}
+		if c.MaxRetryWait > 0 && delay > c.MaxRetryWait {
+			delay = c.MaxRetryWait
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// staleClient returns a copy of c for requests for which a stale entry in
+// the cache can be used instead: they are not retried and time out after
+// StaleTimeout, since waiting for an AUR that is slow or cannot be reached
+// is worse than using the stale entry.
+func (c *Client) staleClient() *Client {
+	q := *c
+	q.MaxRetries = 0
+	timeout := c.HTTPClient.Timeout
+	if c.StaleTimeout > 0 && (timeout == 0 || c.StaleTimeout < timeout) {
+		hc := *c.HTTPClient
+		hc.Timeout = c.StaleTimeout
+		q.HTTPClient = &hc
+	}
+	return &q
+}
+
+// retryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or an HTTP date, and returns how long to wait.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(value); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		if int64(s) > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// rpc performs a request to the RPC interface with the given query
+// parameters and returns the results.
+func (c *Client) rpc(ctx context.Context, query string) ([]*Package, error) {
+	resp, err := c.Get(ctx, c.BaseURL+"/rpc?v=5&"+query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg response
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("cannot decode response from AUR: %w", err)
+	}
+	if msg.Type == "error" {
+		return nil, errors.New(msg.Error)
+	}
+	for _, p := range msg.Results {
+		p.baseURL = c.BaseURL
+	}
+	return msg.Results, nil
+}
+
+// SearchFields are the fields that the AUR can be searched by:
+//
+//	name          the name contains the query
+//	name-desc     the name or the description contains the query
+//	maintainer    the package is maintained by the query
+//	submitter     the package was submitted by the query
+//	depends       the package depends on the query, and similarly for
+//	makedepends   make dependencies, and so on
+//	optdepends
+//	checkdepends
+//	provides      the package is or provides the query
+//	conflicts     the package conflicts with the query
+//	replaces      the package replaces the query
+//	groups        the package is in the group
+//	keywords      the package has the keyword
+var SearchFields = []string{
+	"name",
+	"name-desc",
+	"maintainer",
+	"submitter",
+	"depends",
+	"makedepends",
+	"optdepends",
+	"checkdepends",
+	"provides",
+	"conflicts",
+	"replaces",
+	"groups",
+	"keywords",
+}
+
+// IsSearchField returns true if by is one of SearchFields.
+func IsSearchField(by string) bool {
+	for _, f := range SearchFields {
+		if f == by {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the field by of the package matches the query,
+// in the same way that the AUR searches. See SearchFields for the fields.
+func (p *Package) Matches(by, query string) bool {
+	switch by {
+	case "name":
+		return strings.Contains(strings.ToLower(p.Name), strings.ToLower(query))
+	case "name-desc":
+		q := strings.ToLower(query)
+		return strings.Contains(strings.ToLower(p.Name), q) ||
+			strings.Contains(strings.ToLower(p.Description), q)
+	case "maintainer":
+		return p.Maintainer == query
+	case "submitter":
+		return p.Submitter == query
+	case "depends":
+		return hasDependName(p.Depends, query)
+	case "makedepends":
+		return hasDependName(p.MakeDepends, query)
+	case "optdepends":
+		return hasDependName(p.OptDepends, query)
+	case "checkdepends":
+		return hasDependName(p.CheckDepends, query)
+	case "provides":
+		return p.Name == query || hasDependName(p.Provides, query)
+	case "conflicts":
+		return hasDependName(p.Conflicts, query)
+	case "replaces":
+		return hasDependName(p.Replaces, query)
+	case "groups":
+		return hasString(p.Groups, query)
+	case "keywords":
+		return hasString(p.Keywords, query)
+	default:
+		return false
+	}
+}
+
+// hasDependName returns whether any of the dependencies in xs has the name.
+// Optional dependencies may have a description, which is ignored.
+func hasDependName(xs []string, name string) bool {
+	for _, x := range xs {
+		if i := strings.Index(x, ":"); i != -1 {
+			x = x[:i]
+		}
+		if alpm.ParseDepend(strings.TrimSpace(x)).Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the packages on AUR for which the field by matches the
+// query. The field should be one of SearchFields, otherwise the AUR returns
+// an error.
+//
+// Note that search results only contain basic information about the
+// packages, and in particular no dependencies or provides; use ReadAll
+// to get those.
+func (c *Client) Search(ctx context.Context, by, query string) (Packages, error) {
+	if c.Dump != nil {
+		return c.searchDump(ctx, by, query)
+	}
+
+	key := by + "\x00" + query
+	var cached *cacheEntry
+	if c.Cache != nil {
+		cached = c.Cache.get(c.BaseURL, "search", key)
+		if cached != nil && (c.Offline || c.Cache.fresh(cached)) {
+			return cached.Results, nil
+		}
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("cannot search for %q by %s: %w", query, by, ErrNotCached)
+	}
+
+	v := url.Values{}
+	v.Set("type", "search")
+	v.Set("by", by)
+	v.Set("arg", query)
+	q := c
+	if cached != nil {
+		q = c.staleClient()
+	}
+	results, err := q.rpc(ctx, v.Encode())
+	if err != nil {
+		if cached != nil && isTransient(err) {
+			return cached.Results, nil
+		}
+		return nil, err
+	}
+	if c.Cache != nil {
+		c.Cache.put(c.BaseURL, "search", key, results)
+	}
+	return results, nil
+}
+
+// SearchByName returns the packages on AUR whose name contains query.
+func (c *Client) SearchByName(ctx context.Context, query string) (Packages, error) {
+	return c.Search(ctx, "name", query)
+}
+
+// SearchByProvides returns the packages on AUR that provide the given name.
+func (c *Client) SearchByProvides(ctx context.Context, name string) (Packages, error) {
+	return c.Search(ctx, "provides", name)
+}
+
+// Read reads the information of a single package.
+//
+// If the package cannot be found, (nil, *NotFoundError) is returned.
+func (c *Client) Read(ctx context.Context, pkgname string) (*Package, error) {
+	pkgs, err := c.readAll(ctx, []string{pkgname})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs[0], nil
+}
+
+// ReadAll reads the information of multiple packages at once.
+//
+// If any packages cannot be found, (Packages, *NotFoundError) is returned.
+// That is, all successfully read packages are returned.
+func (c *Client) ReadAll(ctx context.Context, pkgnames []string) (Packages, error) {
+	// We only query at most 200 packages at a time, the limit currently
+	// appears to be 250, but we'll stay well beneath that for now.
+	const limit = 200
+
+	var pkgs Packages
+	nfe := &NotFoundError{
+		Names: make([]string, 0),
+	}
+	for len(pkgnames) > 0 {
+		slice := pkgnames
+		if len(slice) > limit {
+			slice = pkgnames[:limit]
+		}
+		pkgnames = pkgnames[len(slice):]
+
+		p, err := c.readAll(ctx, slice)
+		if err != nil {
+			var e *NotFoundError
+			if !errors.As(err, &e) {
+				// We don't know how to handle this error,
+				// so return directly as-is.
+				return nil, err
+			}
+			nfe.Names = append(nfe.Names, e.Names...)
+		}
+		pkgs = append(pkgs, p...)
+	}
+	if len(nfe.Names) != 0 {
+		return pkgs, nfe
+	}
+	return pkgs, nil
+}
+
+func (c *Client) readAll(ctx context.Context, pkgnames []string) (Packages, error) {
+	var results Packages
+	if c.Dump != nil {
+		pkgs, err := c.dumpPackages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range pkgnames {
+			if p, ok := pkgs[s]; ok {
+				results = append(results, p)
+			}
+		}
+	} else {
+		var err error
+		results, err = c.readCached(ctx, pkgnames)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := make(map[string]bool)
+	for _, p := range results {
+		m[p.Name] = true
+	}
+	nfe := &NotFoundError{}
+	for _, s := range pkgnames {
+		if !m[s] {
+			nfe.Names = append(nfe.Names, s)
+		}
+	}
+	if len(nfe.Names) != 0 {
+		return results, nfe
+	}
+	return results, nil
+}
+
+// readCached reads the packages from the cache while the entries are fresh,
+// and the rest from the AUR. In offline mode, ErrNotCached is returned if
+// any packages are not in the cache.
+func (c *Client) readCached(ctx context.Context, pkgnames []string) (Packages, error) {
+	var results Packages
+	missing := pkgnames
+	stale := make(map[string]*cacheEntry)
+	if c.Cache != nil {
+		missing = nil
+		for _, s := range pkgnames {
+			e := c.Cache.get(c.BaseURL, "info", s)
+			if e != nil && (c.Offline || c.Cache.fresh(e)) {
+				results = append(results, e.Results...)
+				continue
+			}
+			if e != nil {
+				stale[s] = e
+			}
+			missing = append(missing, s)
+		}
+	}
+
+	if len(missing) != 0 && c.Offline {
+		return nil, fmt.Errorf("cannot read %s: %w", strings.Join(missing, ", "), ErrNotCached)
+	}
+	if len(missing) != 0 {
+		q := c
+		if len(stale) == len(missing) {
+			q = c.staleClient()
+		}
+		fetched, err := q.multiinfo(ctx, missing)
+		if err != nil {
+			// Fall back to the stale entries, if we have them all.
+			if len(stale) != len(missing) || !isTransient(err) {
+				return nil, err
+			}
+			for _, e := range stale {
+				fetched = append(fetched, e.Results...)
+			}
+		} else if c.Cache != nil {
+			m := make(map[string]*Package)
+			for _, p := range fetched {
+				m[p.Name] = p
+			}
+			for _, s := range missing {
+				var ps []*Package
+				if p, ok := m[s]; ok {
+					ps = []*Package{p}
+				}
+				c.Cache.put(c.BaseURL, "info", s, ps)
+			}
+		}
+		results = append(results, fetched...)
+	}
+	return results, nil
+}
+
+// multiinfo reads the packages from the AUR.
+func (c *Client) multiinfo(ctx context.Context, pkgnames []string) (Packages, error) {
+	var b strings.Builder
+	b.WriteString("type=multiinfo")
+	for _, s := range pkgnames {
+		b.WriteString("&arg[]=")
+		b.WriteString(url.QueryEscape(s))
+	}
+	return c.rpc(ctx, b.String())
+}
+
+// isTransient returns true if err is an error that might not happen if
+// the request were repeated later, such as when the AUR cannot be reached
+// or is overloaded. Cancellation is not transient.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Snapshot returns the snapshot tarball of the package sources, which is
+// a gzip compressed tar archive. The caller must close it.
+func (c *Client) Snapshot(ctx context.Context, p *Package) (io.ReadCloser, error) {
+	if c.Offline {
+		return nil, ErrOffline
+	}
+	resp, err := c.Get(ctx, p.DownloadURL())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/cassava/repoctl/pacman/aur/aurtest"
+)
+
+func TestClientRetry(z *testing.T) {
+	srv := aurtest.NewServer(&aur.Package{Name: "foo", Version: "1.0-1"})
+	defer srv.Close()
+	c := srv.Client()
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusBadGateway} {
+		before := srv.Requests()
+		srv.FailNext(2, code)
+		p, err := c.Read(context.Background(), "foo")
+		if err != nil {
+			z.Fatalf("unexpected error after %d: %s", code, err)
+		}
+		if p.Name != "foo" {
+			z.Errorf("wrong package returned: %s", p.Name)
+		}
+		if n := srv.Requests() - before; n != 3 {
+			z.Errorf("expected 3 requests after %d, got %d", code, n)
+		}
+	}
+}
+
+func TestClientRetryExhausted(z *testing.T) {
+	srv := aurtest.NewServer(&aur.Package{Name: "foo", Version: "1.0-1"})
+	defer srv.Close()
+	c := srv.Client()
+	c.MaxRetries = 2
+
+	srv.FailNext(5, http.StatusServiceUnavailable)
+	_, err := c.Read(context.Background(), "foo")
+	var se *aur.StatusError
+	if !errors.As(err, &se) {
+		z.Fatalf("expected *StatusError, got %v", err)
+	}
+	if se.StatusCode != http.StatusServiceUnavailable {
+		z.Errorf("wrong status code: %d", se.StatusCode)
+	}
+	if n := srv.Requests(); n != 3 {
+		z.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestClientRetryAfter(z *testing.T) {
+	for _, value := range []string{
+		"3600",
+		"99999999999999999999",
+		time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+	} {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.Header().Set("Retry-After", value)
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		c := aur.NewClient(srv.URL)
+		c.MaxRetryWait = 50 * time.Millisecond
+
+		start := time.Now()
+		resp, err := c.Get(context.Background(), srv.URL)
+		if err != nil {
+			z.Fatalf("%s: unexpected error: %s", value, err)
+		}
+		resp.Body.Close()
+		if d := time.Since(start); d > 5*time.Second {
+			z.Errorf("%s: expected wait to be capped, took %s", value, d)
+		}
+		if requests != 2 {
+			z.Errorf("%s: expected 2 requests, got %d", value, requests)
+		}
+		srv.Close()
+	}
+}
+
+func TestClientNoRetry(z *testing.T) {
+	srv := aurtest.NewServer()
+	defer srv.Close()
+
+	srv.FailNext(1, http.StatusForbidden)
+	_, err := srv.Client().Read(context.Background(), "foo")
+	if err == nil {
+		z.Fatal("expected error, got nil")
+	}
+	if n := srv.Requests(); n != 1 {
+		z.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestClientUserAgent(z *testing.T) {
+	srv := aurtest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+
+	c.SearchByName(context.Background(), "foo")
+	if ua := srv.UserAgent(); ua != aur.DefaultUserAgent {
+		z.Errorf("wrong user agent: %q", ua)
+	}
+	c.UserAgent = "test/1.0"
+	c.SearchByName(context.Background(), "foo")
+	if ua := srv.UserAgent(); ua != "test/1.0" {
+		z.Errorf("wrong user agent: %q", ua)
+	}
+}
+
+func TestClientCancel(z *testing.T) {
+	srv := aurtest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	c.RetryWait = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv.FailNext(1, http.StatusTooManyRequests)
+	_, err := c.Read(ctx, "foo")
+	if !errors.Is(err, context.Canceled) {
+		z.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientSearch(z *testing.T) {
+	srv := aurtest.NewServer(
+		&aur.Package{Name: "foo", Version: "1.0-1", Provides: []string{"bar=1.0"}},
+		&aur.Package{Name: "foo-git", Version: "1.1-1", Depends: []string{"glibc"}},
+	)
+	defer srv.Close()
+	c := srv.Client()
+
+	pkgs, err := c.SearchByName(context.Background(), "foo")
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 2 {
+		z.Errorf("expected 2 packages, got %d", len(pkgs))
+	}
+	pkgs, err = c.SearchByProvides(context.Background(), "bar")
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "foo" {
+		z.Errorf("expected foo to provide bar, got %v", pkgs)
+	}
+	if _, err := c.Search(context.Background(), "color", "foo"); err == nil {
+		z.Errorf("expected error for invalid field, got nil")
+	}
+}
+
+func TestClientSnapshot(z *testing.T) {
+	srv := aurtest.NewServer(&aur.Package{Name: "foo", Version: "1.0-1"})
+	defer srv.Close()
+	c := srv.Client()
+	err := srv.AddSnapshot("foo", map[string]string{
+		"PKGBUILD": "pkgname=foo\n",
+		".SRCINFO": "pkgbase = foo\n",
+	})
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+
+	p, err := c.Read(context.Background(), "foo")
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	rc, err := c.Snapshot(context.Background(), p)
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	defer rc.Close()
+
+	gr, err := gzip.NewReader(rc)
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			z.Fatalf("unexpected error: %s", err)
+		}
+		names = append(names, h.Name)
+	}
+	expect := []string{"foo/", "foo/.SRCINFO", "foo/PKGBUILD"}
+	if len(names) != len(expect) {
+		z.Fatalf("expected %v, got %v", expect, names)
+	}
+	for i := range expect {
+		if names[i] != expect[i] {
+			z.Errorf("expected %v, got %v", expect, names)
+		}
+	}
+}
+
+func TestSearchFields(z *testing.T) {
+	srv := aurtest.NewServer(&aur.Package{Name: "foo", Version: "1.0-1"})
+	defer srv.Close()
+	c := srv.Client()
+
+	for _, by := range aur.SearchFields {
+		if !aur.IsSearchField(by) {
+			z.Errorf("expected %s to be a search field", by)
+		}
+		if _, err := c.Search(context.Background(), by, "foo"); err != nil {
+			z.Errorf("unexpected error searching by %s: %s", by, err)
+		}
+	}
+	if aur.IsSearchField("color") {
+		z.Errorf("expected color not to be a search field")
+	}
+}
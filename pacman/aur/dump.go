@@ -0,0 +1,330 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// NamesDumpPath is the path of the list of all package names on the AUR.
+	NamesDumpPath = "/packages.gz"
+	// MetaDumpPath is the path of the metadata of all packages on the AUR.
+	MetaDumpPath = "/packages-meta-ext-v1.json.gz"
+)
+
+// ErrNoDump is returned by Names if the client does not use a dump.
+var ErrNoDump = errors.New("no AUR dump configured")
+
+// Dump is a local copy of the lists of all packages that the AUR publishes
+// every few minutes, one with only the names and one with all metadata.
+// A client with a dump reads and searches packages locally, which is much
+// faster than asking the AUR for many packages, and works offline.
+//
+// The files are downloaded when they are first needed, and afterwards
+// whenever they are older than MaxAge, but only if they changed.
+type Dump struct {
+	// Dir is the directory that the files are downloaded to.
+	// It is created if it does not exist.
+	Dir string
+	// MaxAge is how long the files are used before the AUR is asked
+	// whether they changed. If negative, the AUR is asked every time.
+	MaxAge time.Duration
+
+	mu    sync.Mutex
+	names []string
+	pkgs  map[string]*Package
+}
+
+// NewDump returns a new dump in dir. If maxAge is zero, DefaultCacheTTL
+// is used.
+func NewDump(dir string, maxAge time.Duration) *Dump {
+	if maxAge == 0 {
+		maxAge = DefaultCacheTTL
+	}
+	return &Dump{Dir: dir, MaxAge: maxAge}
+}
+
+// dumpState is stored next to each downloaded file and records when
+// it was last checked, and what is needed to check whether it changed.
+type dumpState struct {
+	Checked      time.Time `json:"checked"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"last_modified"`
+}
+
+// Names returns the names of all packages on the AUR, from the dump.
+// If the client has no dump, ErrNoDump is returned.
+func (c *Client) Names(ctx context.Context) ([]string, error) {
+	d := c.Dump
+	if d == nil {
+		return nil, ErrNoDump
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.names != nil {
+		return d.names, nil
+	}
+
+	path, _, _, err := c.updateDump(ctx, NamesDumpPath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := openDumpFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	names := make([]string, 0)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read AUR dump %s: %w", path, err)
+	}
+	d.names = names
+	return names, nil
+}
+
+// dumpPackages returns all packages on the AUR by name, from the dump.
+// The metadata is indexed in a file next to the dump, so that it need
+// not be decoded from JSON every time.
+func (c *Client) dumpPackages(ctx context.Context) (map[string]*Package, error) {
+	d := c.Dump
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pkgs != nil {
+		return d.pkgs, nil
+	}
+
+	path, changed, stale, err := c.updateDump(ctx, MetaDumpPath)
+	if err != nil {
+		return nil, err
+	}
+	index := path + ".gob"
+	var pkgs []*Package
+	if !changed {
+		pkgs, err = readDumpIndex(index)
+	}
+	if changed || err != nil {
+		pkgs, err = readDumpJSON(path)
+		if err != nil {
+			return nil, err
+		}
+		writeDumpIndex(index, pkgs)
+	}
+
+	d.pkgs = make(map[string]*Package, len(pkgs))
+	for _, p := range pkgs {
+		p.baseURL = c.BaseURL
+		p.stale = stale
+		d.pkgs[p.Name] = p
+	}
+	return d.pkgs, nil
+}
+
+// searchDump searches the packages in the dump, as Search would.
+func (c *Client) searchDump(ctx context.Context, by, query string) (Packages, error) {
+	if !IsSearchField(by) {
+		return nil, fmt.Errorf("incorrect by field specified: %s", by)
+	}
+	pkgs, err := c.dumpPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results Packages
+	for _, p := range pkgs {
+		if p.Matches(by, query) {
+			results = append(results, p)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+// updateDump downloads the file from the AUR into the dump directory,
+// unless it was checked within MaxAge or it did not change since, and
+// returns the path to the local copy.
+//
+// If the file cannot be downloaded, because the client is offline or the
+// AUR cannot be reached, the local copy is used if there is one, but it
+// is stale. Changed is true if the file was downloaded.
+func (c *Client) updateDump(ctx context.Context, file string) (path string, changed, stale bool, err error) {
+	d := c.Dump
+	path = filepath.Join(d.Dir, filepath.Base(file))
+	statePath := path + ".state"
+
+	var state dumpState
+	_, err = os.Stat(path)
+	exists := err == nil
+	if exists {
+		if bs, err := os.ReadFile(statePath); err == nil {
+			json.Unmarshal(bs, &state)
+		}
+		if time.Since(state.Checked) < d.MaxAge {
+			return path, false, false, nil
+		}
+	}
+	if c.Offline {
+		if exists {
+			return path, false, true, nil
+		}
+		return "", false, false, fmt.Errorf("cannot read AUR dump: %w", ErrOffline)
+	}
+
+	header := make(http.Header)
+	if exists && state.ETag != "" {
+		header.Set("If-None-Match", state.ETag)
+	}
+	if exists && state.LastModified != "" {
+		header.Set("If-Modified-Since", state.LastModified)
+	}
+	resp, err := c.get(ctx, c.BaseURL+file, header)
+	if err != nil {
+		if exists && isTransient(err) {
+			return path, false, true, nil
+		}
+		return "", false, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := writeFile(path, resp.Body); err != nil {
+			return "", false, false, fmt.Errorf("cannot write AUR dump: %w", err)
+		}
+		changed = true
+		state.ETag = resp.Header.Get("ETag")
+		state.LastModified = resp.Header.Get("Last-Modified")
+	}
+	state.Checked = time.Now()
+	if bs, err := json.Marshal(&state); err == nil {
+		writeFile(statePath, strings.NewReader(string(bs)))
+	}
+	return path, changed, false, nil
+}
+
+// writeFile writes r to a temporary file first and then renames it to
+// path, so that nobody reads a partial file.
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// gzipReadCloser closes both the gzip reader and the file beneath it.
+type gzipReadCloser struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (r gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.f.Close()
+}
+
+// openDumpFile opens a file of the dump. The files are compressed with gzip,
+// unless the server sent them with Content-Encoding, in which case they were
+// decompressed on the fly.
+func openDumpFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 2)
+	n, _ := io.ReadFull(f, magic)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if n < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return f, nil
+	}
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipReadCloser{gr, f}, nil
+}
+
+// readDumpJSON reads the packages from the metadata dump, which is a JSON
+// array of packages, as in the results of the RPC interface.
+func readDumpJSON(path string) ([]*Package, error) {
+	r, err := openDumpFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var pkgs []*Package
+	if err := json.NewDecoder(r).Decode(&pkgs); err != nil {
+		return nil, fmt.Errorf("cannot decode AUR dump %s: %w", path, err)
+	}
+	return pkgs, nil
+}
+
+func readDumpIndex(path string) ([]*Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pkgs []*Package
+	err = gob.NewDecoder(bufio.NewReader(f)).Decode(&pkgs)
+	return pkgs, err
+}
+
+// writeDumpIndex writes the index of the metadata dump. Errors are ignored,
+// since the index is only an optimization.
+func writeDumpIndex(path string, pkgs []*Package) {
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(pw)
+		err := gob.NewEncoder(bw).Encode(pkgs)
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	if err := writeFile(path, pr); err != nil {
+		pr.CloseWithError(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aur_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/cassava/repoctl/pacman/aur/aurtest"
+)
+
+func newDumpClient(dir string, maxAge time.Duration) (*aurtest.Server, *aur.Client) {
+	srv := aurtest.NewServer(
+		&aur.Package{Name: "foo", Version: "1.0-1", Maintainer: "alice", Provides: []string{"bar=1.0"}},
+		&aur.Package{Name: "foo-git", Version: "1.1-1", Maintainer: "bob", Depends: []string{"glibc"}},
+	)
+	c := srv.Client()
+	c.Dump = aur.NewDump(dir, maxAge)
+	c.MaxRetries = 0
+	return srv, c
+}
+
+func TestDump(z *testing.T) {
+	srv, c := newDumpClient(z.TempDir(), time.Hour)
+	defer srv.Close()
+	ctx := context.Background()
+
+	names, err := c.Names(ctx)
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if len(names) != 2 || names[0] != "foo" || names[1] != "foo-git" {
+		z.Errorf("wrong names: %v", names)
+	}
+
+	pkgs, err := c.ReadAll(ctx, []string{"foo", "foo-git", "baz"})
+	if nf, ok := err.(*aur.NotFoundError); !ok || len(nf.Names) != 1 || nf.Names[0] != "baz" {
+		z.Errorf("expected baz not to be found, got %v", err)
+	}
+	if len(pkgs) != 2 {
+		z.Fatalf("expected 2 packages, got %d", len(pkgs))
+	}
+	if pkgs[1].Depends[0] != "glibc" || pkgs[0].Stale() {
+		z.Errorf("wrong package from dump: %+v", pkgs[1])
+	}
+	if u := pkgs[0].DownloadURL(); u != srv.URL+"/cgit/aur.git/snapshot/foo.tar.gz" {
+		z.Errorf("wrong download url: %s", u)
+	}
+
+	pkgs, err = c.SearchByProvides(ctx, "bar")
+	if err != nil || len(pkgs) != 1 || pkgs[0].Name != "foo" {
+		z.Errorf("expected foo to provide bar, got %v, %v", pkgs, err)
+	}
+	pkgs, err = c.Search(ctx, "maintainer", "bob")
+	if err != nil || len(pkgs) != 1 || pkgs[0].Name != "foo-git" {
+		z.Errorf("expected foo-git to be maintained by bob, got %v, %v", pkgs, err)
+	}
+
+	// One request for each file, and nothing else.
+	if n := srv.Requests(); n != 2 {
+		z.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestDumpSearch(z *testing.T) {
+	srv, c := newDumpClient(z.TempDir(), time.Hour)
+	defer srv.Close()
+	ctx := context.Background()
+	srv.Add(
+		&aur.Package{Name: "qux", Version: "1.0-1", Description: "a foo for qux", Submitter: "alice",
+			OptDepends: []string{"foo: for foo support"}, Conflicts: []string{"bar<2"}, Keywords: []string{"foo"}},
+	)
+	rpc := srv.Client()
+	rpc.MaxRetries = 0
+
+	// Searching the dump finds the same packages as the AUR does.
+	queries := map[string]string{"name": "foo", "name-desc": "foo", "submitter": "alice", "optdepends": "foo",
+		"provides": "bar", "conflicts": "bar", "keywords": "foo", "depends": "glibc", "groups": "foo"}
+	for _, by := range aur.SearchFields {
+		query, ok := queries[by]
+		if !ok {
+			query = "qux"
+		}
+		want, err := rpc.Search(ctx, by, query)
+		if err != nil {
+			z.Fatalf("unexpected error: %s", err)
+		}
+		got, err := c.Search(ctx, by, query)
+		if err != nil {
+			z.Fatalf("unexpected error: %s", err)
+		}
+		if len(got) != len(want) {
+			z.Errorf("%s %s: expected %d packages, got %d", by, query, len(want), len(got))
+			continue
+		}
+		for i := range want {
+			if got[i].Name != want[i].Name {
+				z.Errorf("%s %s: expected %s, got %s", by, query, want[i].Name, got[i].Name)
+			}
+		}
+	}
+}
+
+func TestDumpConditional(z *testing.T) {
+	dir := z.TempDir()
+	srv, c := newDumpClient(dir, -1)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := c.Read(ctx, "foo"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+
+	// A new client with the same directory asks whether the dump changed,
+	// and uses the index if it did not.
+	c.Dump = aur.NewDump(dir, -1)
+	if _, err := c.Read(ctx, "foo"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+
+	// If the dump changed, it is downloaded again.
+	srv.Add(&aur.Package{Name: "qux", Version: "1.0-1"})
+	c.Dump = aur.NewDump(dir, -1)
+	if _, err := c.Read(ctx, "qux"); err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+
+	// If the AUR cannot be reached, the dump is stale.
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	c.Dump = aur.NewDump(dir, -1)
+	p, err := c.Read(ctx, "qux")
+	if err != nil {
+		z.Fatalf("unexpected error: %s", err)
+	}
+	if !p.Stale() {
+		z.Errorf("expected qux to be stale")
+	}
+
+	// And the same if we are offline.
+	before := srv.Requests()
+	c.Offline = true
+	c.Dump = aur.NewDump(dir, -1)
+	if p, err := c.Read(ctx, "qux"); err != nil || !p.Stale() {
+		z.Errorf("expected stale qux, got %v, %v", p, err)
+	}
+	if n := srv.Requests() - before; n != 0 {
+		z.Errorf("expected no requests, got %d", n)
+	}
+}
+
+func TestDumpOffline(z *testing.T) {
+	srv, c := newDumpClient(z.TempDir(), time.Hour)
+	defer srv.Close()
+	c.Offline = true
+
+	if _, err := c.Names(context.Background()); err == nil {
+		z.Errorf("expected error without dump, got nil")
+	}
+	c.Dump = nil
+	if _, err := c.Names(context.Background()); err != aur.ErrNoDump {
+		z.Errorf("expected ErrNoDump, got %v", err)
+	}
+}
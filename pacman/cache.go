@@ -0,0 +1,199 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// cacheVersion is incremented every time that the Package type or the
+// cache format changes, so that old caches are discarded.
+const cacheVersion = 2
+
+// Cache stores the metadata of package files, so that they do not have
+// to be read again as long as they have not changed. A file is considered
+// unchanged if its path, size, modification time, and inode are the same.
+//
+// A nil *Cache is valid and caches nothing.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	dirty   bool
+}
+
+// cacheEntry is a single package file in the cache.
+type cacheEntry struct {
+	Size    int64
+	ModTime time.Time
+	Inode   uint64
+	Package *Package
+}
+
+// cacheFile is what is written to disk.
+type cacheFile struct {
+	Version int
+	Entries map[string]*cacheEntry
+}
+
+// ReadCache reads the cache at path. If the file does not exist, or was
+// written by a different version of repoctl, an empty cache is returned.
+func ReadCache(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		entries: make(map[string]*cacheEntry),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("read cache %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var cf cacheFile
+	if err := gob.NewDecoder(f).Decode(&cf); err != nil || cf.Version != cacheVersion {
+		// The cache will simply be overwritten.
+		debugf("Discard cache %s\n", path)
+		c.dirty = true
+		return c, nil
+	}
+	if cf.Entries != nil {
+		c.entries = cf.Entries
+	}
+	return c, nil
+}
+
+// Path returns the path that the cache is read from and written to.
+func (c *Cache) Path() string { return c.path }
+
+// Len returns the number of package files in the cache.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Lookup returns the cached package for filename, if info still matches
+// the file that was cached. The returned package is a copy and can be
+// modified freely.
+func (c *Cache) Lookup(filename string, info os.FileInfo) (*Package, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[filename]
+	if !ok || !e.matches(info) {
+		return nil, false
+	}
+	p := *e.Package
+	p.Filename = filename
+	return &p, true
+}
+
+// Store adds the package that was read from filename to the cache,
+// replacing any previous entry.
+func (c *Cache) Store(filename string, info os.FileInfo, pkg *Package) {
+	if c == nil {
+		return
+	}
+	p := *pkg
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[filename] = &cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Inode:   inode(info),
+		Package: &p,
+	}
+	c.dirty = true
+}
+
+// Prune removes all entries in the directory dirpath for which keep
+// returns false.
+func (c *Cache) Prune(dirpath string, keep func(filename string) bool) {
+	if c == nil {
+		return
+	}
+	dirpath = filepath.Clean(dirpath)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for filename := range c.entries {
+		if filepath.Dir(filename) == dirpath && !keep(filename) {
+			delete(c.entries, filename)
+			c.dirty = true
+		}
+	}
+}
+
+// Write writes the cache to disk, if it has changed since it was read.
+// The file is replaced atomically, so that concurrent readers never
+// see a partially written cache.
+func (c *Cache) Write() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	debugf("Write cache %s\n", c.path)
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("write cache %s: %w", c.path, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("write cache %s: %w", c.path, err)
+	}
+	defer os.Remove(f.Name())
+
+	err = gob.NewEncoder(f).Encode(&cacheFile{
+		Version: cacheVersion,
+		Entries: c.entries,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path)
+	}
+	if err != nil {
+		return fmt.Errorf("write cache %s: %w", c.path, err)
+	}
+	c.dirty = false
+	return nil
+}
+
+func (e *cacheEntry) matches(info os.FileInfo) bool {
+	return e.Size == info.Size() &&
+		e.ModTime.Equal(info.ModTime()) &&
+		e.Inode == inode(info)
+}
+
+// inode returns the inode number of the file, or 0 if the platform
+// does not provide it.
+func inode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
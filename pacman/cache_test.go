@@ -0,0 +1,81 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestRepository(t, dir, 5)
+	cachepath := filepath.Join(t.TempDir(), "cache", "test.gob")
+
+	c, err := ReadCache(cachepath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pkgs, err := ReadDir(nil, dir, "", c)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != len(files) {
+		t.Fatalf("expected %d packages, got %d", len(files), len(pkgs))
+	}
+
+	// To prove that the cache is used, we replace a file with garbage of
+	// the same size, and restore the modification time.
+	info, _ := os.Stat(files[0])
+	if err := os.WriteFile(files[0], make([]byte, info.Size()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(files[0], info.ModTime(), info.ModTime())
+
+	c, err = ReadCache(cachepath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Len() != len(files) {
+		t.Fatalf("expected %d cache entries, got %d", len(files), c.Len())
+	}
+	pkgs, err = ReadDir(func(err error) error { return err }, dir, "", c)
+	if err != nil {
+		t.Fatalf("expected files to be read from cache, got: %s", err)
+	}
+	for i, p := range pkgs {
+		if p.Filename != files[i] {
+			t.Errorf("expected package %d to be %s, got %s", i, files[i], p.Filename)
+		}
+	}
+
+	// Without the cache the garbage is noticed.
+	_, err = ReadDir(func(err error) error { return err }, dir, "", nil)
+	if err == nil {
+		t.Errorf("expected error without cache")
+	}
+
+	// Changing the size invalidates the entry, and deleted files are pruned.
+	writeTestPackage(t, dir, filepath.Base(files[0]), testPkgInfoFoo)
+	os.Remove(files[4])
+	pkgs, err = ReadDir(nil, dir, "", c)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 4 || pkgs[0].Name != "foo" {
+		t.Errorf("expected changed file to be read again, got %v", pkgs)
+	}
+	if c.Len() != 4 {
+		t.Errorf("expected deleted file to be pruned, got %d entries", c.Len())
+	}
+
+	// A corrupt cache is discarded.
+	os.WriteFile(cachepath, []byte("garbage"), 0644)
+	c, err = ReadCache(cachepath)
+	if err != nil || c.Len() != 0 {
+		t.Errorf("expected empty cache, got %d entries and error %v", c.Len(), err)
+	}
+}
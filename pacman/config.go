@@ -0,0 +1,395 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth is the maximum depth of nested Include directives,
+// which protects against include cycles.
+const maxIncludeDepth = 10
+
+// Config is the configuration of pacman, as read from pacman.conf.
+//
+// Only the options that are set in the configuration are filled in,
+// with the exception of RootDir, DBPath, and Architecture, which
+// have the same defaults as in pacman. As in pacman, if RootDir is set
+// but DBPath is not, then DBPath is var/lib/pacman/ inside RootDir, and
+// if RootDir, DBPath, GPGDir, LogFile, or XferCommand are set more than
+// once, the first value is used.
+type Config struct {
+	RootDir            string   // RootDir
+	DBPath             string   // DBPath
+	CacheDir           []string // CacheDir
+	HookDir            []string // HookDir
+	GPGDir             string   // GPGDir
+	LogFile            string   // LogFile
+	HoldPkg            []string // HoldPkg
+	IgnorePkg          []string // IgnorePkg
+	IgnoreGroup        []string // IgnoreGroup
+	NoUpgrade          []string // NoUpgrade
+	NoExtract          []string // NoExtract
+	Architecture       []string // Architecture, "auto" is resolved
+	XferCommand        string   // XferCommand
+	CleanMethod        []string // CleanMethod
+	SigLevel           []string // SigLevel
+	LocalFileSigLevel  []string // LocalFileSigLevel
+	RemoteFileSigLevel []string // RemoteFileSigLevel
+	ParallelDownloads  int      // ParallelDownloads
+
+	UseSyslog              bool // UseSyslog
+	Color                  bool // Color
+	CheckSpace             bool // CheckSpace
+	VerbosePkgLists        bool // VerbosePkgLists
+	NoProgressBar          bool // NoProgressBar
+	DisableDownloadTimeout bool // DisableDownloadTimeout
+	ILoveCandy             bool // ILoveCandy
+
+	// Repositories contains the enabled repositories, in the order
+	// that they are given in the configuration.
+	Repositories []*Repository
+}
+
+// Repository is a repository section in the pacman configuration.
+type Repository struct {
+	Name string
+
+	// Servers contains the URLs of the repository, with $repo and $arch
+	// already substituted.
+	Servers []string
+	// CacheServers contains the URLs of cache servers, like Servers.
+	CacheServers []string
+	// SigLevel is the signature level of the repository. If it is empty,
+	// then the global SigLevel of the configuration applies.
+	SigLevel []string
+	// Usage is the usage level of the repository. If it is empty, then
+	// the repository is used for everything.
+	Usage []string
+}
+
+// DefaultConfig returns the configuration that pacman uses when
+// the configuration file does not set anything.
+func DefaultConfig() *Config {
+	return &Config{
+		RootDir:      "/",
+		DBPath:       "/var/lib/pacman/",
+		Architecture: []string{systemArchitecture()},
+	}
+}
+
+// systemArchitecture returns the architecture that pacman would use for
+// "auto", such as "x86_64".
+func systemArchitecture() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64"
+	case "386":
+		return "i686"
+	case "arm64":
+		return "aarch64"
+	case "arm":
+		return "armv7h"
+	case "riscv64":
+		return "riscv64"
+	default:
+		return runtime.GOARCH
+	}
+}
+
+// ReadConfig reads the pacman configuration at path the way pacman does,
+// which includes following Include directives. Include directives may
+// contain glob patterns, as in pacman.
+func ReadConfig(path string) (*Config, error) {
+	return readConfig(path, "/")
+}
+
+// readConfig reads the pacman configuration at path, where the paths of
+// Include directives are relative to root.
+func readConfig(path, root string) (*Config, error) {
+	// The architecture defaults to auto, which is resolved in finish.
+	c := &Config{RootDir: "/", DBPath: "/var/lib/pacman/"}
+	p := &configParser{config: c, root: root}
+	if err := p.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	p.finish()
+	return c, nil
+}
+
+// Repository returns the enabled repository with the given name,
+// or nil if there is no such repository.
+func (c *Config) Repository(name string) *Repository {
+	for _, r := range c.Repositories {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// RepositoryNames returns the names of all enabled repositories in the
+// order they are given in the configuration.
+func (c *Config) RepositoryNames() []string {
+	names := make([]string, len(c.Repositories))
+	for i, r := range c.Repositories {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// LocalDatabasePath returns the path to the database of installed packages.
+func (c *Config) LocalDatabasePath() string {
+	return filepath.Join(c.DBPath, "local")
+}
+
+// SyncDatabasePath returns the path to the synced database of the
+// repository with the given name.
+func (c *Config) SyncDatabasePath(name string) string {
+	return filepath.Join(c.DBPath, "sync", name+".db")
+}
+
+// configParser holds the state of parsing a configuration, which can
+// span several files through Include directives.
+type configParser struct {
+	config  *Config
+	root    string
+	section string
+	repo    *Repository
+
+	// rootdir and dbpath are true if RootDir and DBPath are set in the
+	// configuration, since they have defaults, and DBPath otherwise
+	// depends on RootDir.
+	rootdir bool
+	dbpath  bool
+
+	// servers are only expanded after parsing, when the architecture
+	// is known, which is why they are collected here.
+	servers map[*Repository][][2]string
+}
+
+func (p *configParser) parseFile(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("read pacman config %s: include depth exceeds %d", path, maxIncludeDepth)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read pacman config %s: %w", path, err)
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			p.startSection(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if p.section == "" {
+			return fmt.Errorf("read pacman config %s:%d: option outside of section", path, n)
+		}
+
+		key, value := line, ""
+		if i := strings.IndexByte(line, '='); i != -1 {
+			key = strings.TrimSpace(line[:i])
+			value = strings.TrimSpace(line[i+1:])
+		}
+		if key == "Include" {
+			if err := p.include(value, depth); err != nil {
+				return fmt.Errorf("read pacman config %s:%d: %w", path, n, err)
+			}
+			continue
+		}
+
+		if p.repo == nil {
+			err = p.setOption(key, value)
+		} else {
+			err = p.setRepoOption(key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("read pacman config %s:%d: %w", path, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read pacman config %s: %w", path, err)
+	}
+	return nil
+}
+
+func (p *configParser) include(pattern string, depth int) error {
+	if pattern == "" {
+		return fmt.Errorf("include requires a path")
+	}
+	matches, err := filepath.Glob(filepath.Join(p.root, pattern))
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		debugf("No files match include %s\n", pattern)
+	}
+	for _, m := range matches {
+		if err := p.parseFile(m, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *configParser) startSection(name string) {
+	p.section = name
+	p.repo = nil
+	if name == "options" {
+		return
+	}
+	// A repository that is defined twice is merged, as in pacman.
+	p.repo = p.config.Repository(name)
+	if p.repo == nil {
+		p.repo = &Repository{Name: name}
+		p.config.Repositories = append(p.config.Repositories, p.repo)
+	}
+}
+
+func (p *configParser) setOption(key, value string) error {
+	c := p.config
+	switch key {
+	case "RootDir":
+		if !p.rootdir {
+			c.RootDir = value
+			p.rootdir = true
+		}
+	case "DBPath":
+		if !p.dbpath {
+			c.DBPath = value
+			p.dbpath = true
+		}
+	case "CacheDir":
+		c.CacheDir = append(c.CacheDir, value)
+	case "HookDir":
+		c.HookDir = append(c.HookDir, value)
+	case "GPGDir":
+		if c.GPGDir == "" {
+			c.GPGDir = value
+		}
+	case "LogFile":
+		if c.LogFile == "" {
+			c.LogFile = value
+		}
+	case "HoldPkg":
+		c.HoldPkg = append(c.HoldPkg, strings.Fields(value)...)
+	case "IgnorePkg":
+		c.IgnorePkg = append(c.IgnorePkg, strings.Fields(value)...)
+	case "IgnoreGroup":
+		c.IgnoreGroup = append(c.IgnoreGroup, strings.Fields(value)...)
+	case "NoUpgrade":
+		c.NoUpgrade = append(c.NoUpgrade, strings.Fields(value)...)
+	case "NoExtract":
+		c.NoExtract = append(c.NoExtract, strings.Fields(value)...)
+	case "Architecture":
+		c.Architecture = append(c.Architecture, strings.Fields(value)...)
+	case "XferCommand":
+		if c.XferCommand == "" {
+			c.XferCommand = value
+		}
+	case "CleanMethod":
+		c.CleanMethod = append(c.CleanMethod, strings.Fields(value)...)
+	case "SigLevel":
+		c.SigLevel = append(c.SigLevel, strings.Fields(value)...)
+	case "LocalFileSigLevel":
+		c.LocalFileSigLevel = append(c.LocalFileSigLevel, strings.Fields(value)...)
+	case "RemoteFileSigLevel":
+		c.RemoteFileSigLevel = append(c.RemoteFileSigLevel, strings.Fields(value)...)
+	case "ParallelDownloads":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid value for ParallelDownloads: %q", value)
+		}
+		c.ParallelDownloads = n
+	case "UseSyslog":
+		c.UseSyslog = true
+	case "Color":
+		c.Color = true
+	case "CheckSpace":
+		c.CheckSpace = true
+	case "VerbosePkgLists":
+		c.VerbosePkgLists = true
+	case "NoProgressBar":
+		c.NoProgressBar = true
+	case "DisableDownloadTimeout":
+		c.DisableDownloadTimeout = true
+	case "ILoveCandy":
+		c.ILoveCandy = true
+	default:
+		// Pacman only warns about unknown options, so we do likewise.
+		debugf("Ignoring unknown pacman option %s\n", key)
+	}
+	return nil
+}
+
+func (p *configParser) setRepoOption(key, value string) error {
+	r := p.repo
+	switch key {
+	case "Server", "CacheServer":
+		if value == "" {
+			return fmt.Errorf("%s requires a value", key)
+		}
+		if p.servers == nil {
+			p.servers = make(map[*Repository][][2]string)
+		}
+		p.servers[r] = append(p.servers[r], [2]string{key, value})
+	case "SigLevel":
+		r.SigLevel = append(r.SigLevel, strings.Fields(value)...)
+	case "Usage":
+		r.Usage = append(r.Usage, strings.Fields(value)...)
+	default:
+		debugf("Ignoring unknown option %s in repository %s\n", key, r.Name)
+	}
+	return nil
+}
+
+// finish resolves the architecture and expands the server URLs, which
+// can only be done after the entire configuration has been read.
+func (p *configParser) finish() {
+	c := p.config
+	if !p.dbpath && filepath.Clean(c.RootDir) != "/" {
+		// Like pacman -r, a RootDir moves the default DBPath into it.
+		c.DBPath = filepath.Join(c.RootDir, "var", "lib", "pacman") + "/"
+	}
+	if len(c.Architecture) == 0 {
+		c.Architecture = []string{"auto"}
+	}
+	for i, a := range c.Architecture {
+		if a == "auto" {
+			c.Architecture[i] = systemArchitecture()
+		}
+	}
+
+	for _, repo := range c.Repositories {
+		r := strings.NewReplacer("$arch", c.Architecture[0], "$repo", repo.Name)
+		for _, s := range p.servers[repo] {
+			url := r.Replace(s[1])
+			if s[0] == "Server" {
+				repo.Servers = append(repo.Servers, url)
+			} else {
+				repo.CacheServers = append(repo.CacheServers, url)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	incdir := filepath.Join(dir, "pacman.d")
+	os.MkdirAll(incdir, 0755)
+
+	conf := `# General options
+[options]
+RootDir     = /
+DBPath      = ` + dir + `/db/
+CacheDir    = /var/cache/pacman/pkg/
+HoldPkg     = pacman glibc
+Architecture = x86_64 x86_64_v3
+#IgnorePkg  = ignored
+Color
+ParallelDownloads = 5
+SigLevel    = Required DatabaseOptional
+LocalFileSigLevel = Optional
+
+[core]
+Include = ` + incdir + `/mirrorlist
+
+#[testing]
+#Include = ` + incdir + `/mirrorlist
+
+[extra]
+SigLevel = Optional TrustAll
+Include = ` + incdir + `/mirrorlist
+
+Include = ` + incdir + `/*.conf
+`
+	mirrorlist := `Server = https://mirror.example.com/$repo/os/$arch
+`
+	dropin := `[sirius]
+Server = file:///srv/repo/$repo # local
+Usage = Sync Search
+`
+	os.WriteFile(filepath.Join(dir, "pacman.conf"), []byte(conf), 0644)
+	os.WriteFile(filepath.Join(incdir, "mirrorlist"), []byte(mirrorlist), 0644)
+	os.WriteFile(filepath.Join(incdir, "sirius.conf"), []byte(dropin), 0644)
+
+	c, err := ReadConfig(filepath.Join(dir, "pacman.conf"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if names := c.RepositoryNames(); !reflect.DeepEqual(names, []string{"core", "extra", "sirius"}) {
+		t.Errorf("unexpected repositories: %v", names)
+	}
+	if !reflect.DeepEqual(c.HoldPkg, []string{"pacman", "glibc"}) || len(c.IgnorePkg) != 0 {
+		t.Errorf("unexpected HoldPkg %v or IgnorePkg %v", c.HoldPkg, c.IgnorePkg)
+	}
+	if !c.Color || c.ParallelDownloads != 5 {
+		t.Errorf("expected Color and ParallelDownloads = 5")
+	}
+	if !reflect.DeepEqual(c.SigLevel, []string{"Required", "DatabaseOptional"}) {
+		t.Errorf("unexpected SigLevel: %v", c.SigLevel)
+	}
+	if p := c.SyncDatabasePath("sirius"); p != filepath.Join(dir, "db", "sync", "sirius.db") {
+		t.Errorf("unexpected sync database path: %s", p)
+	}
+	if p := c.LocalDatabasePath(); p != filepath.Join(dir, "db", "local") {
+		t.Errorf("unexpected local database path: %s", p)
+	}
+
+	core := c.Repository("core")
+	if !reflect.DeepEqual(core.Servers, []string{"https://mirror.example.com/core/os/x86_64"}) || len(core.SigLevel) != 0 {
+		t.Errorf("unexpected core repository: %+v", core)
+	}
+	extra := c.Repository("extra")
+	if !reflect.DeepEqual(extra.SigLevel, []string{"Optional", "TrustAll"}) {
+		t.Errorf("unexpected extra SigLevel: %v", extra.SigLevel)
+	}
+	sirius := c.Repository("sirius")
+	if !reflect.DeepEqual(sirius.Servers, []string{"file:///srv/repo/sirius"}) || !reflect.DeepEqual(sirius.Usage, []string{"Sync", "Search"}) {
+		t.Errorf("unexpected sirius repository: %+v", sirius)
+	}
+}
+
+func TestReadConfigIncludeCycle(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "pacman.conf")
+	os.WriteFile(p, []byte("[options]\nInclude = "+p+"\n"), 0644)
+	if _, err := ReadConfig(p); err == nil {
+		t.Errorf("expected error for include cycle")
+	}
+}
+
+func TestReadConfigFirstValue(t *testing.T) {
+	dir := t.TempDir()
+	conf := `[options]
+DBPath = /db/
+LogFile = /var/log/pacman.log
+Include = ` + dir + `/options.conf
+RootDir = /ignored
+`
+	options := `RootDir = /mnt
+DBPath = /other/
+GPGDir = /etc/pacman.d/gnupg/
+LogFile = /other.log
+XferCommand = /usr/bin/curl -o %o %u
+XferCommand = /usr/bin/wget %u
+GPGDir = /other/
+`
+	os.WriteFile(filepath.Join(dir, "pacman.conf"), []byte(conf), 0644)
+	os.WriteFile(filepath.Join(dir, "options.conf"), []byte(options), 0644)
+
+	c, err := ReadConfig(filepath.Join(dir, "pacman.conf"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// As in pacman, the first value is used, also across includes.
+	if c.RootDir != "/mnt" || c.DBPath != "/db/" || c.GPGDir != "/etc/pacman.d/gnupg/" ||
+		c.LogFile != "/var/log/pacman.log" || c.XferCommand != "/usr/bin/curl -o %o %u" {
+		t.Errorf("expected first values, got RootDir %s, DBPath %s, GPGDir %s, LogFile %s, XferCommand %s",
+			c.RootDir, c.DBPath, c.GPGDir, c.LogFile, c.XferCommand)
+	}
+}
+
+func TestReadConfigRootDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		options string
+		root    string
+		dbpath  string
+	}{
+		{"", "/", "/var/lib/pacman/"},
+		{"RootDir = /mnt\n", "/mnt", "/mnt/var/lib/pacman/"},
+		{"RootDir = /mnt/\nDBPath = /db/\n", "/mnt/", "/db/"},
+		{"DBPath = /db/\nRootDir = /mnt\n", "/mnt", "/db/"},
+	}
+	for _, tc := range tests {
+		path := filepath.Join(dir, "pacman.conf")
+		os.WriteFile(path, []byte("[options]\n"+tc.options), 0644)
+		c, err := ReadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if c.RootDir != tc.root || c.DBPath != tc.dbpath {
+			t.Errorf("options %q: expected RootDir %s and DBPath %s, got %s and %s",
+				tc.options, tc.root, tc.dbpath, c.RootDir, c.DBPath)
+		}
+	}
+
+	// The paths of a System are relative to its root.
+	os.MkdirAll(filepath.Join(dir, "etc"), 0755)
+	os.WriteFile(filepath.Join(dir, "etc", "pacman.conf"), []byte("[options]\nRootDir = /mnt\n"), 0644)
+	c, err := (&System{Root: dir}).ReadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := filepath.Join(dir, "mnt", "var", "lib", "pacman"); c.DBPath != want {
+		t.Errorf("expected DBPath %s, got %s", want, c.DBPath)
+	}
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// Edge is a dependency of the package From on the package To.
+type Edge struct {
+	From string
+	To   string
+	Kind DependKind
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Kind)
+}
+
+// Cycle is a set of packages that depend on each other, directly or
+// indirectly, so that there is no order in which they can be built.
+// This is a strongly connected component of the graph.
+type Cycle struct {
+	// Packages are the names of the packages in the cycle, sorted.
+	Packages []string
+	// Edges are all dependencies between the packages in the cycle.
+	Edges []Edge
+}
+
+// newCycles returns the cycles for the strongly connected components
+// in sccs, in the same order.
+func newCycles(g *Graph, sccs [][]graph.Node) []Cycle {
+	cycles := make([]Cycle, len(sccs))
+	for i, scc := range sccs {
+		in := make(map[int64]bool)
+		for _, n := range scc {
+			in[n.ID()] = true
+			cycles[i].Packages = append(cycles[i].Packages, n.(*Node).PkgName())
+		}
+		sort.Strings(cycles[i].Packages)
+
+		for _, n := range scc {
+			iter := g.From(n.ID())
+			for iter.Next() {
+				m := iter.Node()
+				if !in[m.ID()] {
+					continue
+				}
+				cycles[i].Edges = append(cycles[i].Edges, Edge{
+					From: n.(*Node).PkgName(),
+					To:   m.(*Node).PkgName(),
+					Kind: g.DependKindFromTo(n, m),
+				})
+			}
+		}
+		sort.Slice(cycles[i].Edges, func(a, b int) bool {
+			ea, eb := cycles[i].Edges[a], cycles[i].Edges[b]
+			if ea.From != eb.From {
+				return ea.From < eb.From
+			}
+			return ea.To < eb.To
+		})
+	}
+	return cycles
+}
+
+// CycleError is returned by Dependencies when the graph contains one or
+// more cycles, so that there is no valid build order.
+//
+// A cycle can often be broken by ignoring the make or check dependencies
+// of one of the edges with Graph.IgnoreDependency.
+type CycleError struct {
+	Cycles []Cycle
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Cycles) == 1 {
+		return fmt.Sprintf("dependency cycle between %s", strings.Join(e.Cycles[0].Packages, ", "))
+	}
+	return fmt.Sprintf("%d dependency cycles", len(e.Cycles))
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+func TestDependenciesCycles(t *testing.T) {
+	app := aurPkg("app", "1.0-1", []string{"foo"})
+	foo := aurPkg("foo", "1.0-1", []string{"bar"})
+	bar := aurPkg("bar", "1.0-1", nil)
+	bar.MakeDepends = []string{"foo"}
+	bar.CheckDepends = []string{"baz"}
+	baz := aurPkg("baz", "1.0-1", []string{"bar"})
+	aurpkgs := aur.Packages{app, foo, bar, baz}
+
+	f := newTestFactory(nil, nil, aurpkgs)
+	g, err := f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if g.HasName("baz") {
+		t.Errorf("expected check dependencies to be ignored by default")
+	}
+
+	_, aps, _, err := Dependencies(g)
+	var cycles *CycleError
+	if !errors.As(err, &cycles) {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+	if len(aps) != 3 {
+		t.Errorf("expected all packages to be returned, got %v", aps)
+	}
+	if len(cycles.Cycles) != 1 {
+		t.Fatalf("expected one cycle, got %v", cycles.Cycles)
+	}
+	c := cycles.Cycles[0]
+	if !equalStrings(c.Packages, []string{"bar", "foo"}) {
+		t.Errorf("unexpected packages in cycle: %v", c.Packages)
+	}
+	want := []Edge{
+		{From: "bar", To: "foo", Kind: MakeDepends},
+		{From: "foo", To: "bar", Kind: Depends},
+	}
+	if len(c.Edges) != len(want) {
+		t.Fatalf("expected edges %v, got %v", want, c.Edges)
+	}
+	for i := range want {
+		if c.Edges[i] != want[i] {
+			t.Errorf("expected edge %v, got %v", want[i], c.Edges[i])
+		}
+	}
+	if s := c.Edges[0].String(); s != "bar -> foo (makedepends)" {
+		t.Errorf("unexpected edge string: %s", s)
+	}
+	if err.Error() != "dependency cycle between bar, foo" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	// Only make and check dependencies can be ignored to break the cycle.
+	if g.IgnoreDependency("foo", "bar", MakeDepends|CheckDepends) {
+		t.Errorf("expected no make dependency from foo to bar")
+	}
+	if !g.IgnoreDependency("bar", "foo", MakeDepends|CheckDepends) {
+		t.Errorf("expected make dependency from bar to foo")
+	}
+	_, aps, _, err = Dependencies(g)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var order []string
+	for _, p := range aps {
+		order = append(order, p.Name)
+	}
+	if !equalStrings(order, []string{"app", "foo", "bar"}) {
+		t.Errorf("unexpected order: %v", order)
+	}
+
+	// With check dependencies, there are two overlapping cycles,
+	// which form a single strongly connected component.
+	f.SetDependencyKinds(Depends | MakeDepends | CheckDepends)
+	g, err = f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, _, _, err = Dependencies(g)
+	if !errors.As(err, &cycles) {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+	if len(cycles.Cycles) != 1 || !equalStrings(cycles.Cycles[0].Packages, []string{"bar", "baz", "foo"}) {
+		t.Errorf("unexpected cycles: %v", cycles.Cycles)
+	}
+	if len(cycles.Cycles[0].Edges) != 4 {
+		t.Errorf("expected four edges, got %v", cycles.Cycles[0].Edges)
+	}
+}
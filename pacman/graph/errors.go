@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+// UnsatisfiedError describes a dependency whose version constraint is not
+// satisfied by the only package that is available, for example when bar
+// requires foo>=2, but AUR only has foo 1.2.
+type UnsatisfiedError struct {
+	// Package is the name of the package that has the dependency.
+	Package string
+	// Depend is the dependency that cannot be satisfied.
+	Depend alpm.Depend
+	// Found is the package that was found instead.
+	Found pacman.AnyPackage
+}
+
+func newUnsatisfiedError(v *Node, d alpm.Depend, found pacman.AnyPackage) *UnsatisfiedError {
+	return &UnsatisfiedError{
+		Package: v.PkgName(),
+		Depend:  d,
+		Found:   found,
+	}
+}
+
+func (e *UnsatisfiedError) Error() string {
+	where := "repositories have"
+	if _, ok := e.Found.(*aur.Package); ok {
+		where = "AUR has"
+	}
+	return fmt.Sprintf("%s requires %s, but %s %s %s", e.Package, e.Depend, where, e.Found.PkgName(), e.Found.PkgVersion())
+}
+
+// UnsatisfiedErrors is returned by Factory.NewGraph when one or more
+// dependencies cannot be satisfied.
+type UnsatisfiedErrors []*UnsatisfiedError
+
+func (es UnsatisfiedErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+	return fmt.Sprintf("%d dependencies cannot be satisfied", len(es))
+}
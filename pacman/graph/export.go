@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// sourceColors are the fill colors of nodes in exported graphs.
+var sourceColors = map[Source]string{
+	SourceAUR:        "#a6cee3",
+	SourceRepository: "#b2df8a",
+	SourceInstalled:  "#d9d9d9",
+	SourceUnknown:    "#fb9a99",
+}
+
+// sortedNodes returns all nodes in the graph sorted by name.
+func sortedNodes(g *Graph) []*Node {
+	nodes := make([]*Node, 0, len(g.names))
+	for _, id := range g.names {
+		nodes = append(nodes, g.Node(id).(*Node))
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].PkgName() < nodes[j].PkgName() })
+	return nodes
+}
+
+// sortedEdges returns all edges in the graph sorted by the names of
+// the packages.
+func sortedEdges(g *Graph) []Edge {
+	var edges []Edge
+	for _, n := range sortedNodes(g) {
+		iter := g.From(n.ID())
+		for iter.Next() {
+			m := iter.Node().(*Node)
+			edges = append(edges, Edge{
+				From: n.PkgName(),
+				To:   m.PkgName(),
+				Kind: g.DependKindFromTo(n, m),
+			})
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language to w.
+// Nodes are colored by their source, and edges that are not
+// installation dependencies are dashed.
+func WriteDOT(w io.Writer, g *Graph) error {
+	var buf strings.Builder
+	buf.WriteString("digraph dependencies {\n")
+	buf.WriteString("\tnode [shape=box, style=filled];\n")
+	for _, n := range sortedNodes(g) {
+		label := n.PkgName()
+		if v := n.PkgVersion(); v != "" {
+			label += "\\n" + v
+		}
+		fmt.Fprintf(&buf, "\t%q [label=\"%s\", fillcolor=%q];\n", n.PkgName(), label, sourceColors[n.Source()])
+	}
+	for _, e := range sortedEdges(g) {
+		fmt.Fprintf(&buf, "\t%q -> %q", e.From, e.To)
+		if e.Kind&Depends == 0 {
+			fmt.Fprintf(&buf, " [style=dashed, label=%q]", e.Kind.String())
+		}
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart to w.
+// Nodes are colored by their source, and edges that are not
+// installation dependencies are dotted.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	var buf strings.Builder
+	buf.WriteString("flowchart LR\n")
+	ids := make(map[string]string)
+	for i, n := range sortedNodes(g) {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.PkgName()] = id
+		label := n.PkgName()
+		if v := n.PkgVersion(); v != "" {
+			label += " " + v
+		}
+		fmt.Fprintf(&buf, "\t%s[\"%s\"]:::%s\n", id, label, n.Source())
+	}
+	for _, e := range sortedEdges(g) {
+		if e.Kind&Depends == 0 {
+			fmt.Fprintf(&buf, "\t%s -. %s .-> %s\n", ids[e.From], e.Kind, ids[e.To])
+		} else {
+			fmt.Fprintf(&buf, "\t%s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+	for _, s := range []Source{SourceAUR, SourceRepository, SourceInstalled, SourceUnknown} {
+		fmt.Fprintf(&buf, "\tclassDef %s fill:%s\n", s, sourceColors[s])
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// jsonGraph is the structure that WriteJSON writes.
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  Source `json:"source"`
+}
+
+type jsonEdge struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Kinds []string `json:"kinds"`
+}
+
+// WriteJSON writes the graph as JSON to w, with a list of nodes and
+// a list of edges. For example:
+//
+//	{
+//	  "nodes": [
+//	    {"name": "foo", "version": "1.0-1", "source": "aur"},
+//	    {"name": "glibc", "version": "2.39-1", "source": "installed"}
+//	  ],
+//	  "edges": [
+//	    {"from": "foo", "to": "glibc", "kinds": ["depends"]}
+//	  ]
+//	}
+func WriteJSON(w io.Writer, g *Graph) error {
+	out := jsonGraph{
+		Nodes: make([]jsonNode, 0, len(g.names)),
+		Edges: make([]jsonEdge, 0),
+	}
+	for _, n := range sortedNodes(g) {
+		out.Nodes = append(out.Nodes, jsonNode{
+			Name:    n.PkgName(),
+			Version: n.PkgVersion(),
+			Source:  n.Source(),
+		})
+	}
+	for _, e := range sortedEdges(g) {
+		out.Edges = append(out.Edges, jsonEdge{
+			From:  e.From,
+			To:    e.To,
+			Kinds: e.Kind.Names(),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+// newExportGraph returns a small graph with packages of every source:
+//
+//	app -> lib (aur), app -> python (installed), lib -> cmake (repository,
+//	make dependency only), lib -> ghost (unknown).
+func newExportGraph(t *testing.T) *Graph {
+	sync := pacman.Packages{
+		repoPkg("python", "3.12.1-1"),
+		repoPkg("cmake", "3.28.1-1"),
+	}
+	local := pacman.Packages{
+		repoPkg("python", "3.12.1-1"),
+	}
+	app := aurPkg("app", "1.0-1", []string{"lib", "python"})
+	lib := aurPkg("lib", "2.0-1", []string{"ghost"})
+	lib.MakeDepends = []string{"cmake"}
+
+	f := newTestFactory(local, sync, aur.Packages{app, lib})
+	g, err := f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return g
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, newExportGraph(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := `digraph dependencies {
+	node [shape=box, style=filled];
+	"app" [label="app\n1.0-1", fillcolor="#a6cee3"];
+	"cmake" [label="cmake\n3.28.1-1", fillcolor="#b2df8a"];
+	"ghost" [label="ghost", fillcolor="#fb9a99"];
+	"lib" [label="lib\n2.0-1", fillcolor="#a6cee3"];
+	"python" [label="python\n3.12.1-1", fillcolor="#d9d9d9"];
+	"app" -> "lib";
+	"app" -> "python";
+	"lib" -> "cmake" [style=dashed, label="makedepends"];
+	"lib" -> "ghost";
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMermaid(&buf, newExportGraph(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := buf.String()
+	for _, s := range []string{
+		"flowchart LR\n",
+		"\tn0[\"app 1.0-1\"]:::aur\n",
+		"\tn2[\"ghost\"]:::unknown\n",
+		"\tn0 --> n3\n",
+		"\tn3 -. makedepends .-> n1\n",
+		"\tclassDef installed fill:#d9d9d9\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected output to contain %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, newExportGraph(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var out jsonGraph
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("cannot read output: %s", err)
+	}
+	if len(out.Nodes) != 5 || len(out.Edges) != 4 {
+		t.Fatalf("unexpected graph: %+v", out)
+	}
+	if n := out.Nodes[4]; n.Name != "python" || n.Source != SourceInstalled {
+		t.Errorf("unexpected node: %+v", n)
+	}
+	if e := out.Edges[2]; e.From != "lib" || e.To != "cmake" || !equalStrings(e.Kinds, []string{"makedepends"}) {
+		t.Errorf("unexpected edge: %+v", e)
+	}
+}
@@ -1,10 +1,12 @@
 package graph
 
 import (
+	"errors"
 	"os"
-	"regexp"
+	"sort"
 
 	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
 	"github.com/cassava/repoctl/pacman/aur"
 	"github.com/goulash/errs"
 )
@@ -16,20 +18,26 @@ import (
 // packages available in repositories. This reduces the
 // dependency list
 type Factory struct {
-	local map[string]*pacman.Package
-	sync  map[string]*pacman.Package
+	local *index
+	sync  *index
 
 	// Options
 	skipInstalled bool
 	truncate      bool
 	noUnknown     bool
-	depFunc       func(pacman.AnyPackage) []string
+	prefer        []string
+	kinds         DependKind
+
+	// AUR access, which can be replaced for testing.
+	aurReadAll  func([]string) (aur.Packages, error)
+	aurProvides func(string) (aur.Packages, error)
 
 	// Statistics
 	aurCalls int
 }
 
 // NewFactory returns a new dependency graph, ignoring repositories given in `ignore`.
+// The installed and available packages are read from the pacman system sys.
 //
 // Note that it makes a difference between dependencies that are in AUR, and those
 // availabe in the repositories. Ignoring repositories effectively "demotes" any
@@ -38,66 +46,53 @@ type Factory struct {
 // Any package that is in the dependency graph that is not from AUR is treated
 // as a leaf in the graph, since we assume that pacman can resolve those
 // dependencies.
-func NewFactory(ignoreRepos ...string) (*Factory, error) {
-	re := regexp.MustCompile(`(=|>|<).*$`)
-	f := Factory{
-		skipInstalled: false,
-		truncate:      false,
-		noUnknown:     false,
-		depFunc: func(p pacman.AnyPackage) []string {
-			deps := make([]string, 0, len(p.PkgDepends())+len(p.PkgMakeDepends()))
-			deps = append(deps, p.PkgDepends()...)
-			deps = append(deps, p.PkgMakeDepends()...)
-			for i, p := range deps {
-				// Quote from: https://wiki.archlinux.org/index.php/PKGBUILD
-				// > Version restrictions can be specified with comparison
-				// > operators, e.g. depends=('foobar>=1.8.0'); if multiple
-				// > restrictions are needed, the dependency can be repeated for
-				// > each, e.g. depends=('foobar>=1.8.0' 'foobar<2.0.0').
-				if re.MatchString(p) {
-					deps[i] = re.ReplaceAllLiteralString(p, "")
-				}
-			}
-			return deps
-		},
-	}
+func NewFactory(sys *pacman.System, ignoreRepos ...string) (*Factory, error) {
+	f := newFactory()
 
 	// Read local database
-	lpkgs, err := pacman.ReadLocalDatabase(errs.Print(os.Stderr))
+	lpkgs, err := sys.ReadLocalDatabase(errs.Print(os.Stderr))
 	if err != nil {
 		return nil, err
 	}
-	f.local = lpkgs.ToMap()
+	f.local = newIndex(lpkgs)
 
 	// Read available packages
+	c, err := sys.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
 	var pkgs pacman.Packages
-	if len(ignoreRepos) == 0 {
-		pkgs, err = pacman.ReadAllSyncDatabases()
-		if err != nil {
-			return nil, err
+nextRepo:
+	for _, repo := range c.RepositoryNames() {
+		for _, ig := range ignoreRepos {
+			if repo == ig {
+				continue nextRepo
+			}
 		}
-	} else {
-		enabled, err := pacman.EnabledRepositories()
+
+		rpkgs, err := pacman.ReadDatabase(c.SyncDatabasePath(repo))
 		if err != nil {
 			return nil, err
 		}
-	nextRepo:
-		for _, repo := range enabled {
-			for _, ig := range ignoreRepos {
-				if repo == ig {
-					continue nextRepo
-				}
-			}
+		pkgs = append(pkgs, rpkgs...)
+	}
+	f.sync = newIndex(pkgs)
+	return f, nil
+}
 
-			rpkgs, err := pacman.ReadSyncDatabase(repo)
-			if err != nil {
-				return nil, err
-			}
-			pkgs = append(pkgs, rpkgs...)
-		}
+func newFactory() *Factory {
+	return &Factory{
+		local: newIndex(nil),
+		sync:  newIndex(nil),
+
+		skipInstalled: false,
+		truncate:      false,
+		noUnknown:     false,
+		kinds:         Depends | MakeDepends,
+
+		aurReadAll:  aur.ReadAll,
+		aurProvides: aur.SearchByProvides,
 	}
-	f.sync = pkgs.ToMap()
-	return &f, nil
 }
 
 // SetSkipInstalled controls whether installed packages are
@@ -119,10 +114,45 @@ func (f *Factory) SetNoUnknown(yes bool) {
 	f.noUnknown = yes
 }
 
-// SetDependencyFunc controls which dependency list is used.
+// SetPreferredProviders sets the names of packages that are preferred, in
+// the given order, when a dependency is provided by more than one package.
+//
+// After these, installed packages are preferred, then packages from the
+// repositories in the order of the pacman configuration, and finally
+// packages from AUR by name.
+func (f *Factory) SetPreferredProviders(names ...string) {
+	f.prefer = names
+}
+
+// SetDependencyKinds controls which kinds of dependencies are followed.
 // By default, make and install dependencies are included.
-func (f *Factory) SetDependencyFunc(fn func(pacman.AnyPackage) []string) {
-	f.depFunc = fn
+func (f *Factory) SetDependencyKinds(kinds DependKind) {
+	f.kinds = kinds
+}
+
+// dependencies returns the dependencies of p that the factory follows,
+// together with their kind. A package may be listed more than once,
+// if it is a dependency of more than one kind.
+func (f *Factory) dependencies(p pacman.AnyPackage) []kindDepend {
+	var deps []kindDepend
+	add := func(kind DependKind, ss []string) {
+		if f.kinds&kind == 0 {
+			return
+		}
+		for _, s := range ss {
+			deps = append(deps, kindDepend{alpm.ParseDepend(s), kind})
+		}
+	}
+	add(Depends, p.PkgDepends())
+	add(MakeDepends, p.PkgMakeDepends())
+	add(CheckDepends, p.Pkg().CheckDepends)
+	return deps
+}
+
+// kindDepend is a dependency together with its kind.
+type kindDepend struct {
+	depend alpm.Depend
+	kind   DependKind
 }
 
 // NumRequestsAUR returns the number of requests made to AUR.
@@ -133,99 +163,321 @@ func (f *Factory) NumRequestsAUR() int {
 // NewGraph returns a dependency graph of the given AUR packages.
 // Extra packages may be pulled into the graph to properly build
 // the dependency graph.
+//
+// Dependencies are satisfied either by a package with the same name or
+// by a package that provides it, and version constraints are respected.
+// If the only packages that are available do not satisfy a version
+// constraint, they are not added to the graph. Instead, the graph is
+// returned together with UnsatisfiedErrors, which lists all of these.
 func (f *Factory) NewGraph(pkgs aur.Packages) (*Graph, error) {
 	g := NewGraph()
+	var unsatisfied UnsatisfiedErrors
+
+	// provided remembers which nodes provide a name, in the order they
+	// were added, so that a dependency that they satisfy reuses them.
+	provided := make(map[string][]*Node)
+	addNode := func(p pacman.AnyPackage) *Node {
+		u := g.NewNode(p)
+		u.Installed = f.local.byName[p.PkgName()] != nil
+		g.AddNode(u)
+		for _, s := range p.Pkg().Provides {
+			name := alpm.ParseDepend(s).Name
+			provided[name] = append(provided[name], u)
+		}
+		return u
+	}
+	provider := func(d alpm.Depend) *Node {
+		for _, u := range provided[d.Name] {
+			if satisfies(u.AnyPackage, d) {
+				return u
+			}
+		}
+		return nil
+	}
 
 	lst := make([]*Node, 0, len(pkgs))
 	for _, p := range pkgs {
-		v := g.NewNode(p)
-		lst = append(lst, v)
-		g.AddNode(v)
+		lst = append(lst, addNode(p))
 	}
 
 	// As long as we have new packages to process, continue.
 	for len(lst) != 0 {
 		discovered := make([]*Node, 0)
-		unavailable := make(map[string]bool, 0)
-		pending := make(map[string][]*Node)
+		pending := make(map[string][]pendingEdge)
 
 		// For each package to add edges for:
 		for _, v := range lst {
-			for _, d := range f.depFunc(v.AnyPackage) {
-				if g.HasName(d) {
-					// Dependency already in the graph, so add the edge:
-					u := g.NodeWithName(d)
-					g.AddEdgeFromTo(v, u)
-					continue
-				}
+			for _, kd := range f.dependencies(v.AnyPackage) {
+				d := kd.depend
 
-				if f.skipInstalled {
-					if _, ok := f.local[d]; ok {
+				// Dependency already in the graph, so add the edge,
+				// provided that it satisfies the constraint.
+				if u := g.NodeWithName(d.Name); u != nil {
+					if !satisfies(u.AnyPackage, d) {
+						unsatisfied = append(unsatisfied, newUnsatisfiedError(v, d, u.AnyPackage))
 						continue
 					}
+					g.AddDependency(v, u, kd.kind)
+					continue
+				}
+
+				// A provider in the graph is only reused if it satisfies
+				// the constraint; otherwise another one is looked for.
+				if u := provider(d); u != nil {
+					g.AddDependency(v, u, kd.kind)
+					continue
+				}
+
+				if f.skipInstalled && len(f.local.candidates(d)) != 0 {
+					continue
 				}
 
-				if p, ok := f.sync[d]; ok {
-					u := g.NewNode(p)
+				if cs := f.sync.candidates(d); len(cs) != 0 {
+					u := addNode(f.choose(d, cs))
 					if !f.truncate {
 						// Process this package for dependencies
 						discovered = append(discovered, u)
 					}
-					g.AddNode(u)
-					g.AddEdgeFromTo(v, u)
+					g.AddDependency(v, u, kd.kind)
+					continue
+				}
+				if p := f.sync.byName[d.Name]; p != nil {
+					// The package is in a repository, but not in a version
+					// that we can use, and it won't be in AUR either.
+					unsatisfied = append(unsatisfied, newUnsatisfiedError(v, d, p))
 					continue
 				}
 
-				// If we got this fas, then d is an unknown dependency,
+				// If we got this far, then d is an unknown dependency,
 				// and therefore must be in AUR (otherwise we're in trouble).
 				// We haven't added an edge for this yet, so we need to remember that.
-				unavailable[d] = true
-				pending[d] = append(pending[d], v)
+				pending[d.Name] = append(pending[d.Name], pendingEdge{v, d, kd.kind})
 			}
 		}
+		if len(pending) == 0 {
+			lst = discovered
+			continue
+		}
 
-		// This may be called for AUR or unknown packages
-		addFetchedPkg := func(p pacman.AnyPackage) *Node {
-			u := g.NewNode(p)
-			g.AddNode(u)
-			for _, v := range pending[u.PkgName()] {
-				g.AddEdgeFromTo(v, u)
+		// addFetchedPkg adds p to the graph, together with the edges that
+		// we remembered for name that it satisfies. Only nodes that are new
+		// are returned, since these need to be processed.
+		// This may be called for AUR or unknown packages.
+		addFetchedPkg := func(name string, p pacman.AnyPackage) *Node {
+			var edges []pendingEdge
+			for _, e := range pending[name] {
+				if satisfies(p, e.depend) {
+					edges = append(edges, e)
+				} else {
+					unsatisfied = append(unsatisfied, newUnsatisfiedError(e.from, e.depend, p))
+				}
+			}
+			if len(edges) == 0 {
+				return nil
+			}
+			u := g.NodeWithName(p.PkgName())
+			isNew := u == nil
+			if isNew {
+				u = addNode(p)
+			}
+			for _, e := range edges {
+				g.AddDependency(e.from, u, e.kind)
+			}
+			if !isNew {
+				return nil
 			}
 			return u
 		}
 
 		// Get all unavailable packages from AUR:
-		fromAUR := make([]string, 0, len(unavailable))
-		for k := range unavailable {
+		fromAUR := make([]string, 0, len(pending))
+		for k := range pending {
 			fromAUR = append(fromAUR, k)
 		}
+		sort.Strings(fromAUR)
 		f.aurCalls++
-		pkgs, err := aur.ReadAll(fromAUR)
+		aps, err := f.aurReadAll(fromAUR)
+		if err != nil && !aur.IsNotFound(err) {
+			return nil, err
+		}
 
 		// Add the AUR packages to the graph and to the list of new packages.
 		// Also add the edges that we remembered.
-		if err != nil {
-			if !f.noUnknown && aur.IsNotFound(err) {
-				// the unknown packages are not found in AUR...
-				for _, p := range err.(*aur.NotFoundError).Names {
-					addFetchedPkg(&pacman.Package{
-						Name:        p,
-						Origin:      pacman.UnknownOrigin,
-						Depends:     make([]string, 0),
-						MakeDepends: make([]string, 0),
-					})
-				}
-			} else {
-				return nil, err
+		for _, p := range aps {
+			if u := addFetchedPkg(p.Name, p); u != nil {
+				discovered = append(discovered, u)
 			}
 		}
-		for _, p := range pkgs {
-			u := addFetchedPkg(p)
-			discovered = append(discovered, u)
+
+		// The packages that are not on AUR may be provided by others.
+		var nfe *aur.NotFoundError
+		if errors.As(err, &nfe) {
+			for _, name := range nfe.Names {
+				p, err := f.findProvider(name, pending[name])
+				if err != nil {
+					return nil, err
+				}
+				if p != nil {
+					if u := addFetchedPkg(name, p); u != nil {
+						discovered = append(discovered, u)
+					}
+					continue
+				}
+
+				if f.noUnknown {
+					return nil, &aur.NotFoundError{Names: []string{name}}
+				}
+				addFetchedPkg(name, &pacman.Package{
+					Name:        name,
+					Origin:      pacman.UnknownOrigin,
+					Depends:     make([]string, 0),
+					MakeDepends: make([]string, 0),
+				})
+			}
 		}
 
 		lst = discovered
 	}
 
+	if len(unsatisfied) != 0 {
+		return g, unsatisfied
+	}
 	return g, nil
 }
+
+// pendingEdge is an edge to a dependency that we have yet to find.
+type pendingEdge struct {
+	from   *Node
+	depend alpm.Depend
+	kind   DependKind
+}
+
+// findProvider returns the preferred package from AUR that provides
+// name and satisfies all the edges, or nil if there is none.
+func (f *Factory) findProvider(name string, edges []pendingEdge) (pacman.AnyPackage, error) {
+	f.aurCalls++
+	results, err := f.aurProvides(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	// Search results do not contain the provides, so we need to read
+	// the packages in full.
+	names := make([]string, len(results))
+	for i, p := range results {
+		names[i] = p.Name
+	}
+	sort.Strings(names)
+	f.aurCalls++
+	aps, err := f.aurReadAll(names)
+	if err != nil && !aur.IsNotFound(err) {
+		return nil, err
+	}
+
+	var cs []pacman.AnyPackage
+nextPkg:
+	for _, p := range aps {
+		for _, e := range edges {
+			if !satisfies(p, e.depend) {
+				continue nextPkg
+			}
+		}
+		cs = append(cs, p)
+	}
+	if len(cs) == 0 {
+		if len(aps) != 0 {
+			// Let the caller report why the first one does not fit.
+			return aps[0], nil
+		}
+		return nil, nil
+	}
+	return f.choose(alpm.Depend{Name: name}, cs), nil
+}
+
+// choose returns the preferred package out of the candidates, which
+// all satisfy the dependency d.
+//
+// As in pacman, a package with the name of the dependency is always
+// chosen. Otherwise, the order of preference is: the preferred providers
+// in the order given, installed packages, and then the order of the
+// candidates.
+func (f *Factory) choose(d alpm.Depend, cs []pacman.AnyPackage) pacman.AnyPackage {
+	for _, p := range cs {
+		if p.PkgName() == d.Name {
+			return p
+		}
+	}
+
+	rank := func(p pacman.AnyPackage) int {
+		for i, name := range f.prefer {
+			if p.PkgName() == name {
+				return i
+			}
+		}
+		if f.local.byName[p.PkgName()] != nil {
+			return len(f.prefer)
+		}
+		return len(f.prefer) + 1
+	}
+
+	best := cs[0]
+	for _, p := range cs[1:] {
+		if rank(p) < rank(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// satisfies returns whether p satisfies the dependency d.
+func satisfies(p pacman.AnyPackage, d alpm.Depend) bool {
+	if p.Pkg().Origin == pacman.UnknownOrigin {
+		// We don't know anything about it, so we can't say it doesn't.
+		return true
+	}
+	return d.SatisfiedBy(p.PkgName(), p.PkgVersion(), p.Pkg().Provides)
+}
+
+// index makes it possible to find packages by name and by what
+// they provide.
+type index struct {
+	byName     map[string]*pacman.Package
+	byProvides map[string]pacman.Packages
+}
+
+func newIndex(pkgs pacman.Packages) *index {
+	x := &index{
+		byName:     make(map[string]*pacman.Package),
+		byProvides: make(map[string]pacman.Packages),
+	}
+	for _, p := range pkgs {
+		// As in pacman, the first repository wins.
+		if _, ok := x.byName[p.Name]; ok {
+			continue
+		}
+		x.byName[p.Name] = p
+		for _, s := range p.Provides {
+			name := alpm.ParseDepend(s).Name
+			x.byProvides[name] = append(x.byProvides[name], p)
+		}
+	}
+	return x
+}
+
+// candidates returns the packages that satisfy d. A package with the
+// same name comes first, followed by the providers in index order.
+func (x *index) candidates(d alpm.Depend) []pacman.AnyPackage {
+	var cs []pacman.AnyPackage
+	if p := x.byName[d.Name]; p != nil && p.Satisfies(d) {
+		cs = append(cs, p)
+	}
+	for _, p := range x.byProvides[d.Name] {
+		if p.Name != d.Name && p.Satisfies(d) {
+			cs = append(cs, p)
+		}
+	}
+	return cs
+}
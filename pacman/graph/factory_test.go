@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+// newTestFactory returns a factory with the given installed and sync
+// packages, where AUR consists of the given AUR packages.
+func newTestFactory(local, sync pacman.Packages, aurpkgs aur.Packages) *Factory {
+	f := newFactory()
+	f.local = newIndex(local)
+	f.sync = newIndex(sync)
+	f.aurReadAll = func(names []string) (aur.Packages, error) {
+		var pkgs aur.Packages
+		nfe := &aur.NotFoundError{}
+	nextName:
+		for _, n := range names {
+			for _, p := range aurpkgs {
+				if p.Name == n {
+					pkgs = append(pkgs, p)
+					continue nextName
+				}
+			}
+			nfe.Names = append(nfe.Names, n)
+		}
+		if len(nfe.Names) != 0 {
+			return pkgs, nfe
+		}
+		return pkgs, nil
+	}
+	f.aurProvides = func(name string) (aur.Packages, error) {
+		var pkgs aur.Packages
+		for _, p := range aurpkgs {
+			if p.Pkg().Satisfies(alpm.Depend{Name: name}) {
+				pkgs = append(pkgs, p)
+			}
+		}
+		return pkgs, nil
+	}
+	return f
+}
+
+func repoPkg(name, version string, provides ...string) *pacman.Package {
+	return &pacman.Package{
+		Name:     name,
+		Version:  version,
+		Origin:   pacman.DatabaseOrigin,
+		Provides: provides,
+	}
+}
+
+func aurPkg(name, version string, depends []string, provides ...string) *aur.Package {
+	return &aur.Package{
+		Name:        name,
+		PackageBase: name,
+		Version:     version,
+		Depends:     depends,
+		Provides:    provides,
+	}
+}
+
+// nodeNames returns the sorted names of all nodes in the graph.
+func nodeNames(g *Graph) []string {
+	var names []string
+	for name := range g.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasEdge returns whether there is an edge from the package named a to b.
+func hasEdge(g *Graph, a, b string) bool {
+	u, v := g.NodeWithName(a), g.NodeWithName(b)
+	return u != nil && v != nil && g.HasEdgeFromTo(u.ID(), v.ID())
+}
+
+func TestFactoryProvides(t *testing.T) {
+	sync := pacman.Packages{
+		repoPkg("mailcap", "2.1-1", "mime-types"),
+		repoPkg("nginx-mainline", "1.25-1", "nginx"),
+		repoPkg("jre-openjdk", "21-1", "java-runtime=21"),
+		repoPkg("jre17-openjdk", "17-1", "java-runtime=17"),
+	}
+	local := pacman.Packages{
+		repoPkg("jre17-openjdk", "17-1", "java-runtime=17"),
+	}
+	app := aurPkg("app", "1.0-1", []string{"mime-types", "java-runtime>=17", "libfoo.so", "nginx"})
+	aurpkgs := aur.Packages{
+		app,
+		aurPkg("foo-git", "r1.abc-1", nil, "libfoo.so=1-64", "foo"),
+	}
+
+	f := newTestFactory(local, sync, aurpkgs)
+	g, err := f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"app", "foo-git", "jre17-openjdk", "mailcap", "nginx-mainline"}
+	if got := nodeNames(g); !equalStrings(got, want) {
+		t.Errorf("expected nodes %v, got %v", want, got)
+	}
+	for _, n := range want[1:] {
+		if !hasEdge(g, "app", n) {
+			t.Errorf("expected edge from app to %s", n)
+		}
+	}
+	_, _, unknown, err := Dependencies(g)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("expected no unknown packages, got %v", unknown)
+	}
+
+	// Preferred providers take precedence over installed packages.
+	f.SetPreferredProviders("jre-openjdk")
+	g, err = f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !hasEdge(g, "app", "jre-openjdk") || g.HasName("jre17-openjdk") {
+		t.Errorf("expected preferred provider, got %v", nodeNames(g))
+	}
+
+	// Installed providers are skipped.
+	f.SetPreferredProviders()
+	f.SetSkipInstalled(true)
+	g, err = f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if g.HasName("jre17-openjdk") {
+		t.Errorf("expected installed provider to be skipped, got %v", nodeNames(g))
+	}
+}
+
+func TestFactoryVersions(t *testing.T) {
+	sync := pacman.Packages{
+		repoPkg("python", "3.11.5-1"),
+	}
+	bar := aurPkg("bar", "1.0-1", []string{"foo>=2", "python>=3.12"})
+	baz := aurPkg("baz", "1.0-1", []string{"foo"})
+	aurpkgs := aur.Packages{
+		bar, baz,
+		aurPkg("foo", "1.2-1", nil),
+	}
+
+	f := newTestFactory(nil, sync, aurpkgs)
+	g, err := f.NewGraph(aur.Packages{bar, baz})
+	var unsatisfied UnsatisfiedErrors
+	if !errors.As(err, &unsatisfied) {
+		t.Fatalf("expected UnsatisfiedErrors, got %v", err)
+	}
+	if len(unsatisfied) != 2 {
+		t.Fatalf("expected two unsatisfied dependencies, got %v", unsatisfied)
+	}
+	for _, e := range unsatisfied {
+		if e.Package != "bar" {
+			t.Errorf("expected bar to be unsatisfied, got %s", e.Package)
+		}
+		switch e.Depend.Name {
+		case "foo":
+			if e.Error() != "bar requires foo>=2, but AUR has foo 1.2-1" {
+				t.Errorf("unexpected error message: %s", e)
+			}
+		case "python":
+			if e.Error() != "bar requires python>=3.12, but repositories have python 3.11.5-1" {
+				t.Errorf("unexpected error message: %s", e)
+			}
+		default:
+			t.Errorf("unexpected unsatisfied dependency: %s", e.Depend)
+		}
+	}
+
+	// The graph is still returned, with foo only for baz.
+	if g == nil {
+		t.Fatalf("expected graph")
+	}
+	if !hasEdge(g, "baz", "foo") || hasEdge(g, "bar", "foo") || hasEdge(g, "bar", "python") {
+		t.Errorf("unexpected edges in graph")
+	}
+}
+
+func TestFactoryProviderVersions(t *testing.T) {
+	sync := pacman.Packages{
+		repoPkg("jre17-openjdk", "17-1", "java-runtime=17"),
+		repoPkg("jre-openjdk", "21-1", "java-runtime=21"),
+	}
+	old := aurPkg("old", "1.0-1", []string{"java-runtime=17"})
+	app := aurPkg("app", "1.0-1", []string{"java-runtime>=21"})
+	tool := aurPkg("tool", "1.0-1", []string{"java-runtime>=21"})
+
+	// The provider chosen for old does not satisfy app, so another
+	// provider is chosen, which tool then reuses.
+	f := newTestFactory(nil, sync, aur.Packages{old, app, tool})
+	g, err := f.NewGraph(aur.Packages{old, app, tool})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"app", "jre-openjdk", "jre17-openjdk", "old", "tool"}
+	if got := nodeNames(g); !equalStrings(got, want) {
+		t.Errorf("expected nodes %v, got %v", want, got)
+	}
+	if !hasEdge(g, "old", "jre17-openjdk") || !hasEdge(g, "app", "jre-openjdk") || !hasEdge(g, "tool", "jre-openjdk") {
+		t.Errorf("unexpected edges in graph")
+	}
+	if len(g.names) != g.Nodes().Len() {
+		t.Errorf("expected no duplicate nodes")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
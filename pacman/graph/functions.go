@@ -4,18 +4,35 @@ import (
 	"github.com/cassava/repoctl/pacman"
 	"github.com/cassava/repoctl/pacman/aur"
 
+	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/topo"
 )
 
 // Dependencies returns a list of all dependencies in the graph,
 // those in repositories, those from AUR, and those unknown.
-func Dependencies(g *Graph) (pacman.Packages, aur.Packages, []string) {
+// Packages come before their dependencies.
+//
+// If the graph contains cycles, the lists are still returned, with the
+// packages of each cycle in sorted order where the cycle would be,
+// together with a *CycleError. In this case, the order is not a valid
+// build order.
+func Dependencies(g *Graph) (pacman.Packages, aur.Packages, []string, error) {
 	rps := make(pacman.Packages, 0)
 	aps := make(aur.Packages, 0)
 	ups := make([]string, 0)
 
+	nodes, err := topo.Sort(g)
+	var cerr error
+	if sccs, ok := err.(topo.Unorderable); ok {
+		// Each cycle is replaced by nil in nodes, in the same order.
+		cycles := newCycles(g, sccs)
+		nodes = fillCycles(g, nodes, cycles)
+		cerr = &CycleError{Cycles: cycles}
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
 	names := make(map[string]bool)
-	nodes, _ := topo.Sort(g)
 	for _, vn := range nodes {
 		n := vn.(*Node)
 		if names[n.PkgName()] {
@@ -36,5 +53,22 @@ func Dependencies(g *Graph) (pacman.Packages, aur.Packages, []string) {
 			panic("unexpected type of package in graph")
 		}
 	}
-	return rps, aps, ups
+	return rps, aps, ups, cerr
+}
+
+// fillCycles replaces each nil in nodes with the nodes of the cycle.
+func fillCycles(g *Graph, nodes []graph.Node, cycles []Cycle) []graph.Node {
+	result := make([]graph.Node, 0, len(nodes))
+	i := 0
+	for _, n := range nodes {
+		if n != nil {
+			result = append(result, n)
+			continue
+		}
+		for _, name := range cycles[i].Packages {
+			result = append(result, g.NodeWithName(name))
+		}
+		i++
+	}
+	return result
 }
@@ -21,6 +21,8 @@
 package graph
 
 import (
+	"strings"
+
 	"github.com/cassava/repoctl/pacman"
 	"github.com/cassava/repoctl/pacman/aur"
 
@@ -32,6 +34,9 @@ import (
 type Node struct {
 	simple.Node
 	pacman.AnyPackage
+
+	// Installed is true if a package with the same name is installed.
+	Installed bool
 }
 
 // IsFromAUR returns whether the node comes from AUR.
@@ -40,6 +45,31 @@ func (n *Node) IsFromAUR() bool {
 	return ok
 }
 
+// Source describes where the package of a node comes from.
+type Source string
+
+const (
+	SourceAUR        Source = "aur"
+	SourceRepository Source = "repository"
+	SourceInstalled  Source = "installed"
+	SourceUnknown    Source = "unknown"
+)
+
+// Source returns where the package of the node comes from.
+// Packages from AUR are always SourceAUR, even if they are installed.
+func (n *Node) Source() Source {
+	switch {
+	case n.IsFromAUR():
+		return SourceAUR
+	case n.Pkg().Origin == pacman.UnknownOrigin:
+		return SourceUnknown
+	case n.Installed:
+		return SourceInstalled
+	default:
+		return SourceRepository
+	}
+}
+
 // AllDepends returns a (newly created) string slice of the installation
 // and make dependencies of this package.
 func (n *Node) AllDepends() []string {
@@ -59,11 +89,44 @@ func (n *Node) String() string {
 	return n.PkgName()
 }
 
+// DependKind is the kind of dependency that an edge in the graph stands
+// for. Since a package can depend on another in several ways, the kinds
+// are flags that can be combined.
+type DependKind int
+
+const (
+	Depends DependKind = 1 << iota
+	MakeDepends
+	CheckDepends
+)
+
+// String returns the kinds of dependencies by their PKGBUILD names,
+// such as "depends, makedepends".
+func (k DependKind) String() string {
+	return strings.Join(k.Names(), ", ")
+}
+
+// Names returns the PKGBUILD names of the kinds of dependencies.
+func (k DependKind) Names() []string {
+	var names []string
+	if k&Depends != 0 {
+		names = append(names, "depends")
+	}
+	if k&MakeDepends != 0 {
+		names = append(names, "makedepends")
+	}
+	if k&CheckDepends != 0 {
+		names = append(names, "checkdepends")
+	}
+	return names
+}
+
 // Graph implements graph.Graph.
 type Graph struct {
 	*simple.DirectedGraph
 
 	names map[string]int64
+	kinds map[[2]int64]DependKind
 }
 
 // NewGraph returns a new graph.
@@ -71,6 +134,7 @@ func NewGraph() *Graph {
 	return &Graph{
 		DirectedGraph: simple.NewDirectedGraph(),
 		names:         make(map[string]int64),
+		kinds:         make(map[[2]int64]DependKind),
 	}
 }
 
@@ -122,6 +186,42 @@ func (g *Graph) AddNode(v graph.Node) {
 }
 
 // AddEdgeFromTo adds a directed edge from u to v.
+// The edge is treated as an installation dependency.
 func (g *Graph) AddEdgeFromTo(u, v graph.Node) {
+	g.AddDependency(u, v, Depends)
+}
+
+// AddDependency adds a directed edge from u to v, meaning that u depends on v
+// in the given way. If there already is such an edge, the kind is added to it.
+func (g *Graph) AddDependency(u, v graph.Node, kind DependKind) {
 	g.SetEdge(g.NewEdge(u, v))
+	g.kinds[[2]int64{u.ID(), v.ID()}] |= kind
+}
+
+// DependKindFromTo returns how u depends on v, or 0 if it doesn't.
+func (g *Graph) DependKindFromTo(u, v graph.Node) DependKind {
+	return g.kinds[[2]int64{u.ID(), v.ID()}]
+}
+
+// IgnoreDependency removes the given kinds of dependency of the package
+// named from on the package named to. If no kind remains, the edge is
+// removed. This can be used to break cycles, for example by ignoring
+// make and check dependencies.
+//
+// It returns false if there is no edge that has one of the kinds.
+func (g *Graph) IgnoreDependency(from, to string, kinds DependKind) bool {
+	u, v := g.NodeWithName(from), g.NodeWithName(to)
+	if u == nil || v == nil {
+		return false
+	}
+	key := [2]int64{u.ID(), v.ID()}
+	if g.kinds[key]&kinds == 0 {
+		return false
+	}
+	g.kinds[key] &^= kinds
+	if g.kinds[key] == 0 {
+		delete(g.kinds, key)
+		g.RemoveEdge(u.ID(), v.ID())
+	}
+	return true
 }
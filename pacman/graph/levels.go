@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/cassava/repoctl/pacman/aur"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// BuildLevels returns the AUR packages in the graph grouped by levels,
+// so that each package only depends on packages in earlier levels.
+// All packages in a level can therefore be built in parallel once the
+// earlier levels have been built. Packages in a level are sorted by name.
+//
+// Packages from the repositories are not part of any level, since pacman
+// installs them, but dependencies of AUR packages through them are
+// respected. If the graph contains cycles, a *CycleError is returned.
+func BuildLevels(g *Graph) ([]aur.Packages, error) {
+	nodes, err := topo.Sort(g)
+	if sccs, ok := err.(topo.Unorderable); ok {
+		return nil, &CycleError{Cycles: newCycles(g, sccs)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Packages come before their dependencies in nodes, so we go through
+	// them backwards, which ensures that the levels of the dependencies
+	// are known. The level of a node is the number of AUR packages on the
+	// longest path to a leaf, not counting itself.
+	height := make(map[int64]int)
+	var levels []aur.Packages
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i].(*Node)
+		h := 0
+		iter := g.From(n.ID())
+		for iter.Next() {
+			m := iter.Node().(*Node)
+			hm := height[m.ID()]
+			if m.IsFromAUR() {
+				hm++
+			}
+			if hm > h {
+				h = hm
+			}
+		}
+		height[n.ID()] = h
+
+		if p, ok := n.AnyPackage.(*aur.Package); ok {
+			for len(levels) <= h {
+				levels = append(levels, make(aur.Packages, 0))
+			}
+			levels[h] = append(levels[h], p)
+		}
+	}
+	for _, lvl := range levels {
+		sort.Slice(lvl, func(i, j int) bool { return lvl[i].Name < lvl[j].Name })
+	}
+	return levels, nil
+}
+
+// AURDependencies returns the sorted names of the AUR packages that the
+// package with the given name depends on, either directly or through
+// packages that are not from AUR.
+func AURDependencies(g *Graph, name string) []string {
+	n := g.NodeWithName(name)
+	if n == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[int64]bool)
+	var visit func(graph.Node)
+	visit = func(u graph.Node) {
+		iter := g.From(u.ID())
+		for iter.Next() {
+			m := iter.Node().(*Node)
+			if seen[m.ID()] {
+				continue
+			}
+			seen[m.ID()] = true
+			if m.IsFromAUR() {
+				names = append(names, m.PkgName())
+			} else {
+				visit(m)
+			}
+		}
+	}
+	visit(n)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+func TestBuildLevels(t *testing.T) {
+	sync := pacman.Packages{
+		repoPkg("python", "3.12.1-1"),
+	}
+	app := aurPkg("app", "1.0-1", []string{"lib-a", "lib-b", "python"})
+	tool := aurPkg("tool", "1.0-1", []string{"lib-b"})
+	aurpkgs := aur.Packages{
+		app, tool,
+		aurPkg("lib-a", "1.0-1", []string{"lib-c"}),
+		aurPkg("lib-b", "1.0-1", []string{"python"}),
+		aurPkg("lib-c", "1.0-1", nil),
+	}
+
+	f := newTestFactory(nil, sync, aurpkgs)
+	g, err := f.NewGraph(aur.Packages{app, tool})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	levels, err := BuildLevels(g)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := [][]string{
+		{"lib-b", "lib-c"},
+		{"lib-a", "tool"},
+		{"app"},
+	}
+	if len(levels) != len(want) {
+		t.Fatalf("expected %d levels, got %d", len(want), len(levels))
+	}
+	for i, lvl := range levels {
+		var got []string
+		for _, p := range lvl {
+			got = append(got, p.Name)
+		}
+		if !equalStrings(got, want[i]) {
+			t.Errorf("expected level %d to be %v, got %v", i, want[i], got)
+		}
+	}
+
+	if got := AURDependencies(g, "app"); !equalStrings(got, []string{"lib-a", "lib-b"}) {
+		t.Errorf("unexpected AUR dependencies of app: %v", got)
+	}
+	if got := AURDependencies(g, "lib-c"); len(got) != 0 {
+		t.Errorf("unexpected AUR dependencies of lib-c: %v", got)
+	}
+
+	// Cycles are reported.
+	g.AddDependency(g.NodeWithName("lib-c"), g.NodeWithName("app"), MakeDepends)
+	_, err = BuildLevels(g)
+	var cycles *CycleError
+	if !errors.As(err, &cycles) {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Path is a chain of dependencies, where each edge starts at the
+// package where the previous one ended.
+type Path []Edge
+
+// String returns the path in the form "foo -> bar -> baz", where edges
+// that are not installation dependencies are marked with their kind,
+// such as "foo -> bar [makedepends] -> baz".
+func (p Path) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(p[0].From)
+	for _, e := range p {
+		b.WriteString(" -> ")
+		b.WriteString(e.To)
+		if e.Kind&Depends == 0 {
+			b.WriteString(" [" + e.Kind.String() + "]")
+		}
+	}
+	return b.String()
+}
+
+// ShortestPaths returns all shortest paths of dependencies from the package
+// named from to the package named to, sorted by their string representation.
+// If there is no such path, nil is returned.
+func ShortestPaths(g *Graph, from, to string) []Path {
+	u, v := g.NodeWithName(from), g.NodeWithName(to)
+	if u == nil || v == nil || u.ID() == v.ID() {
+		return nil
+	}
+
+	// Breadth-first search from u, remembering for each node all the
+	// nodes through which it can be reached in the least number of steps.
+	dist := map[int64]int{u.ID(): 0}
+	prev := make(map[int64][]*Node)
+	queue := []*Node{u}
+	for len(queue) != 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n.ID() == v.ID() {
+			continue
+		}
+		iter := g.From(n.ID())
+		for iter.Next() {
+			m := iter.Node().(*Node)
+			d, ok := dist[m.ID()]
+			if !ok {
+				dist[m.ID()] = dist[n.ID()] + 1
+				queue = append(queue, m)
+			} else if d != dist[n.ID()]+1 {
+				continue
+			}
+			prev[m.ID()] = append(prev[m.ID()], n)
+		}
+	}
+	if _, ok := dist[v.ID()]; !ok {
+		return nil
+	}
+
+	// Walk back from v to u through all predecessors.
+	var paths []Path
+	var walk func(n *Node, suffix Path)
+	walk = func(n *Node, suffix Path) {
+		if n.ID() == u.ID() {
+			paths = append(paths, append(Path(nil), suffix...))
+			return
+		}
+		for _, p := range prev[n.ID()] {
+			e := Edge{From: p.PkgName(), To: n.PkgName(), Kind: g.DependKindFromTo(p, n)}
+			walk(p, append(Path{e}, suffix...))
+		}
+	}
+	walk(v, nil)
+	sort.Slice(paths, func(i, j int) bool { return paths[i].String() < paths[j].String() })
+	return paths
+}
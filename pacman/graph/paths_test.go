@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+func TestShortestPaths(t *testing.T) {
+	app := aurPkg("app", "1.0-1", []string{"a", "b", "d"})
+	aurpkgs := aur.Packages{
+		app,
+		aurPkg("a", "1.0-1", []string{"c"}),
+		aurPkg("b", "1.0-1", []string{"c"}),
+		aurPkg("c", "1.0-1", []string{"d"}),
+		aurPkg("d", "1.0-1", nil),
+	}
+	aurpkgs[2].Depends = nil
+	aurpkgs[2].MakeDepends = []string{"c"}
+
+	f := newTestFactory(nil, nil, aurpkgs)
+	g, err := f.NewGraph(aur.Packages{app})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var got []string
+	for _, p := range ShortestPaths(g, "app", "c") {
+		got = append(got, p.String())
+	}
+	want := []string{"app -> a -> c", "app -> b -> c [makedepends]"}
+	if !equalStrings(got, want) {
+		t.Errorf("expected paths %q, got %q", want, got)
+	}
+
+	if ps := ShortestPaths(g, "app", "d"); len(ps) != 1 || ps[0].String() != "app -> d" {
+		t.Errorf("expected direct path to d, got %v", ps)
+	}
+	if ps := ShortestPaths(g, "d", "app"); ps != nil {
+		t.Errorf("expected no path from d to app, got %v", ps)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/goulash/archive"
+)
+
+// Inspection contains everything that can be read from a package file:
+// the package information, how it was built, and what files it contains.
+type Inspection struct {
+	*Package
+
+	// BuildInfo is read from .BUILDINFO, and is nil if the package
+	// does not have one, which is the case for old packages.
+	BuildInfo *BuildInfo
+	// Mtree is read from .MTREE, and is nil if the package does not
+	// have one. It contains entries for the metadata files.
+	Mtree []*MtreeEntry
+	// Files contains the files in the package, in the same format as
+	// returned by ReadFileList.
+	Files []string
+}
+
+// Inspect reads all information from a pacman package at once.
+func Inspect(filename string) (*Inspection, error) {
+	debugf("Inspect package %s\n", filename)
+	d, err := archive.NewDecompressor(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	defer d.Close()
+
+	var in Inspection
+	tr := tar.NewReader(d)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read package %s: %w", filename, err)
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		switch name {
+		case ".PKGINFO":
+			in.Package, err = readFilePkgInfo(tr)
+		case ".BUILDINFO":
+			in.BuildInfo, err = readBuildInfo(tr)
+		case ".MTREE":
+			in.Mtree, err = readMtree(tr)
+		default:
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if hdr.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") {
+				name += "/"
+			}
+			in.Files = append(in.Files, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read package %s: %s: %w", filename, name, err)
+		}
+	}
+	if in.Package == nil {
+		return nil, fmt.Errorf("read package %s: cannot find file \".PKGINFO\"", filename)
+	}
+
+	in.Filename = filename
+	in.Origin = FileOrigin
+	sort.Strings(in.Files)
+	return &in, nil
+}
+
+// ReadBuildInfo reads the .BUILDINFO file from a pacman package.
+func ReadBuildInfo(filename string) (*BuildInfo, error) {
+	bs, err := archive.ReadFileFromArchive(filename, ".BUILDINFO")
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	info, err := readBuildInfo(bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	return info, nil
+}
+
+// ReadMtree reads the .MTREE file from a pacman package.
+func ReadMtree(filename string) ([]*MtreeEntry, error) {
+	bs, err := archive.ReadFileFromArchive(filename, ".MTREE")
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	entries, err := readMtree(bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testBuildInfo = `format = 2
+pkgname = foo
+pkgbase = foo
+pkgver = 1.0-1
+pkgarch = any
+pkgbuild_sha256sum = 0123456789abcdef
+packager = Nobody <nobody@example.com>
+builddate = 1700000000
+builddir = /build
+startdir = /startdir
+buildtool = devtools
+buildtoolver = 1:1.2.0-1-any
+buildenv = !distcc
+buildenv = color
+options = strip
+options = !debug
+installed = bar-2.1-3-x86_64
+installed = glibc-2.38-7-x86_64
+`
+
+const testMtree = `#mtree
+/set type=file uid=0 gid=0 mode=644
+./.BUILDINFO time=1700000000.0 size=400 md5digest=aa sha256digest=bb
+./usr time=1700000000.0 mode=755 type=dir
+./usr/bin time=1700000000.0 mode=755 type=dir
+./usr/bin/foo time=1700000000.500000000 mode=755 size=1234 md5digest=cc sha256digest=dd
+./usr/bin/foo\040bar time=1700000000.0 type=link link=foo
+`
+
+func TestInspect(t *testing.T) {
+	var mtree bytes.Buffer
+	gw := gzip.NewWriter(&mtree)
+	gw.Write([]byte(testMtree))
+	gw.Close()
+
+	p := filepath.Join(t.TempDir(), "foo-1.0-1-any.pkg.tar.gz")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, e := range []struct {
+		name string
+		data []byte
+	}{
+		{".BUILDINFO", []byte(testBuildInfo)},
+		{".MTREE", mtree.Bytes()},
+		{".PKGINFO", []byte(testPkgInfoFoo)},
+		{"usr/", nil},
+		{"usr/bin/", nil},
+		{"usr/bin/foo", []byte("#!/bin/sh\n")},
+	} {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data))}
+		if e.data == nil {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		tw.WriteHeader(hdr)
+		tw.Write(e.data)
+	}
+	tw.Close()
+	zw.Close()
+	f.Close()
+
+	in, err := Inspect(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if in.Name != "foo" || in.Version != "1.0-1" || in.Filename != p || in.Origin != FileOrigin {
+		t.Errorf("unexpected package: %+v", in.Package)
+	}
+	if want := []string{"usr/", "usr/bin/", "usr/bin/foo"}; !reflect.DeepEqual(in.Files, want) {
+		t.Errorf("expected files %q, got %q", want, in.Files)
+	}
+
+	bi := in.BuildInfo
+	if bi == nil {
+		t.Fatalf("expected build info")
+	}
+	if bi.Format != 2 || bi.BuildDir != "/build" || bi.BuildToolVersion != "1:1.2.0-1-any" || !bi.BuildDate.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected build info: %+v", bi)
+	}
+	if !reflect.DeepEqual(bi.BuildEnv, []string{"!distcc", "color"}) || !reflect.DeepEqual(bi.Installed, []string{"bar-2.1-3-x86_64", "glibc-2.38-7-x86_64"}) {
+		t.Errorf("unexpected build environment: %+v", bi)
+	}
+
+	if len(in.Mtree) != 5 {
+		t.Fatalf("expected 5 mtree entries, got %d", len(in.Mtree))
+	}
+	foo := in.Mtree[3]
+	if foo.Path != "usr/bin/foo" || foo.Type != "file" || foo.Mode != 0755 || foo.Size != 1234 || foo.SHA256Digest != "dd" {
+		t.Errorf("unexpected mtree entry: %+v", foo)
+	}
+	if !foo.Time.Equal(time.Unix(1700000000, 500000000)) {
+		t.Errorf("unexpected mtree time: %s", foo.Time)
+	}
+	if link := in.Mtree[4]; link.Path != "usr/bin/foo bar" || link.Type != "link" || link.Link != "foo" || link.Mode != 0644 {
+		t.Errorf("unexpected mtree entry: %+v", link)
+	}
+
+	if _, err := ReadBuildInfo(p); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if entries, err := ReadMtree(p); err != nil || len(entries) != 5 {
+		t.Errorf("unexpected result from ReadMtree: %d entries, %v", len(entries), err)
+	}
+}
+
+func TestParseMtreeTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"1700000000", time.Unix(1700000000, 0)},
+		{"1700000000.0", time.Unix(1700000000, 0)},
+		{"1700000000.5", time.Unix(1700000000, 5)},
+		{"1700000000.500000000", time.Unix(1700000000, 500000000)},
+		{"1700000000.1234567890", time.Unix(1700000000, 999999999)},
+		{"1700000000.99999999999999999999", time.Unix(1700000000, 999999999)},
+	}
+	for _, tc := range tests {
+		got, err := parseMtreeTime(tc.in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.in, err)
+		} else if !got.Equal(tc.want) {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.want, got)
+		}
+	}
+	for _, in := range []string{"", "x", "1700000000.x", "1700000000.-1"} {
+		if _, err := parseMtreeTime(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
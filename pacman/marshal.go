@@ -0,0 +1,121 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MarshalPkgInfo returns the package in the .PKGINFO format, with the
+// fields in the same order as makepkg writes them. Unknown fields from
+// Extras are written at the end, sorted by key.
+//
+// For a .PKGINFO that was written by makepkg, the result is the same as
+// the original, including the comments at the start.
+func MarshalPkgInfo(p *Package) []byte {
+	var buf bytes.Buffer
+	for _, c := range p.Comments {
+		fmt.Fprintln(&buf, c)
+	}
+	kv := func(key string, values ...string) {
+		for _, v := range values {
+			fmt.Fprintf(&buf, "%s = %s\n", key, v)
+		}
+	}
+
+	// Like makepkg, the single-valued fields are always written,
+	// even if they are empty.
+	kv("pkgname", p.Name)
+	kv("pkgbase", p.Base)
+	kv("xdata", p.Xdata...)
+	kv("pkgver", p.Version)
+	kv("pkgdesc", p.Description)
+	kv("url", p.URL)
+	kv("builddate", formatBuildDate(p.BuildDate))
+	kv("packager", p.Packager)
+	kv("size", strconv.FormatUint(p.InstalledSize, 10))
+	kv("arch", p.Arch)
+	kv("license", p.License...)
+	kv("replaces", p.Replaces...)
+	kv("group", p.Groups...)
+	kv("conflict", p.Conflicts...)
+	kv("provides", p.Provides...)
+	kv("backup", p.Backups...)
+	kv("depend", p.Depends...)
+	kv("optdepend", p.OptionalDepends...)
+	kv("makedepend", p.MakeDepends...)
+	kv("checkdepend", p.CheckDepends...)
+	kv("makepkgopt", p.MakeOptions...)
+	for _, k := range sortedKeys(p.Extras) {
+		kv(k, p.Extras[k]...)
+	}
+	return buf.Bytes()
+}
+
+// MarshalDesc returns the package in the format of a desc file in a
+// database, with the fields in the same order as repo-add writes them.
+// XDATA and unknown fields from Extras are written at the end, the
+// latter sorted by key.
+//
+// The Size of the package is written as CSIZE, which is only correct
+// for packages that were read from a database.
+func MarshalDesc(p *Package) []byte {
+	var filename string
+	if p.Filename != "" {
+		filename = filepath.Base(p.Filename)
+	}
+
+	var buf bytes.Buffer
+	writeDescField(&buf, "FILENAME", filename)
+	writeDescField(&buf, "NAME", p.Name)
+	writeDescField(&buf, "BASE", p.Base)
+	writeDescField(&buf, "VERSION", p.Version)
+	writeDescField(&buf, "DESC", p.Description)
+	writeDescField(&buf, "GROUPS", p.Groups...)
+	writeDescField(&buf, "CSIZE", strconv.FormatUint(p.Size, 10))
+	writeDescField(&buf, "ISIZE", strconv.FormatUint(p.InstalledSize, 10))
+	writeDescField(&buf, "MD5SUM", p.MD5Sum)
+	writeDescField(&buf, "SHA256SUM", p.SHA256Sum)
+	writeDescField(&buf, "PGPSIG", p.PGPSignature)
+	writeDescField(&buf, "URL", p.URL)
+	writeDescField(&buf, "LICENSE", p.License...)
+	writeDescField(&buf, "ARCH", p.Arch)
+	writeDescField(&buf, "BUILDDATE", formatBuildDate(p.BuildDate))
+	writeDescField(&buf, "PACKAGER", p.Packager)
+	writeDescField(&buf, "REPLACES", p.Replaces...)
+	writeDescField(&buf, "CONFLICTS", p.Conflicts...)
+	writeDescField(&buf, "PROVIDES", p.Provides...)
+	writeDescField(&buf, "DEPENDS", p.Depends...)
+	writeDescField(&buf, "OPTDEPENDS", p.OptionalDepends...)
+	writeDescField(&buf, "MAKEDEPENDS", p.MakeDepends...)
+	writeDescField(&buf, "CHECKDEPENDS", p.CheckDepends...)
+	writeDescField(&buf, "XDATA", p.Xdata...)
+	for _, k := range sortedKeys(p.Extras) {
+		writeDescField(&buf, k, p.Extras[k]...)
+	}
+	return buf.Bytes()
+}
+
+// formatBuildDate returns t as a Unix timestamp, or 0 if t is zero.
+func formatBuildDate(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
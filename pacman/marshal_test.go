@@ -0,0 +1,193 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"strings"
+	"testing"
+)
+
+// testPkgInfoFull is a .PKGINFO as makepkg writes it, without the
+// comments at the top.
+const testPkgInfoFull = `pkgname = python-foo
+pkgbase = foo
+xdata = pkgtype=split
+pkgver = 1:2.0.1-3
+pkgdesc = A package with = in its description
+url = https://example.com
+builddate = 1700000000
+packager = Nobody <nobody@example.com>
+size = 4096
+arch = x86_64
+license = MIT
+license = Apache-2.0 OR GPL-2.0-or-later
+replaces = python-oldfoo
+group = foo-group
+conflict = python-foo-git
+provides = python-foo-git=2.0.1
+backup = etc/foo.conf
+depend = python>=3.11
+depend = glibc
+optdepend = python-bar: for bar support
+makedepend = python-build
+checkdepend = python-pytest
+makepkgopt = strip
+makepkgopt = !debug
+`
+
+// testDescFull is a database desc file as repo-add writes it.
+const testDescFull = `%FILENAME%
+python-foo-1:2.0.1-3-x86_64.pkg.tar.zst
+
+%NAME%
+python-foo
+
+%BASE%
+foo
+
+%VERSION%
+1:2.0.1-3
+
+%DESC%
+A package with = in its description
+
+%GROUPS%
+foo-group
+
+%CSIZE%
+1024
+
+%ISIZE%
+4096
+
+%MD5SUM%
+d41d8cd98f00b204e9800998ecf8427e
+
+%SHA256SUM%
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+
+%URL%
+https://example.com
+
+%LICENSE%
+MIT
+Apache-2.0 OR GPL-2.0-or-later
+
+%ARCH%
+x86_64
+
+%BUILDDATE%
+1700000000
+
+%PACKAGER%
+Nobody <nobody@example.com>
+
+%REPLACES%
+python-oldfoo
+
+%CONFLICTS%
+python-foo-git
+
+%PROVIDES%
+python-foo-git=2.0.1
+
+%DEPENDS%
+python>=3.11
+glibc
+
+%OPTDEPENDS%
+python-bar: for bar support
+
+%MAKEDEPENDS%
+python-build
+
+%CHECKDEPENDS%
+python-pytest
+
+`
+
+func TestMarshalPkgInfo(t *testing.T) {
+	p, err := readFilePkgInfo(strings.NewReader(testPkgInfoFull))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(p.License) != 2 || p.License[1] != "Apache-2.0 OR GPL-2.0-or-later" {
+		t.Errorf("unexpected licenses: %q", p.License)
+	}
+	if len(p.Xdata) != 1 || p.Xdata[0] != "pkgtype=split" {
+		t.Errorf("unexpected xdata: %q", p.Xdata)
+	}
+	if p.Description != "A package with = in its description" {
+		t.Errorf("unexpected description: %q", p.Description)
+	}
+	if got := string(MarshalPkgInfo(p)); got != testPkgInfoFull {
+		t.Errorf("marshal is not the same as input:\n%s", got)
+	}
+
+	// Like makepkg, empty values are written nonetheless, and read again
+	// as empty values.
+	empty := string(MarshalPkgInfo(&Package{Name: "empty"}))
+	if !strings.Contains(empty, "\nurl = \n") {
+		t.Errorf("expected empty url to be written:\n%s", empty)
+	}
+	if !strings.Contains(empty, "\nbuilddate = 0\n") {
+		t.Errorf("expected zero builddate to be written as 0:\n%s", empty)
+	}
+	if p, err := readFilePkgInfo(strings.NewReader(empty)); err != nil || p.URL != "" || p.Name != "empty" {
+		t.Errorf("unexpected result reading empty values: %v, %v", p, err)
+	}
+
+	// Comments at the start and unknown fields are kept.
+	input := "# Generated by makepkg 9.0\n# using fakeroot version 1.36\n" + testPkgInfoFull + "sbom = spdx\nsbom = cyclonedx\n"
+	p, err = readFilePkgInfo(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error with unknown fields: %s", err)
+	}
+	if got := p.Extras["sbom"]; len(got) != 2 || got[1] != "cyclonedx" {
+		t.Errorf("unexpected extras: %q", p.Extras)
+	}
+	if got := string(MarshalPkgInfo(p)); got != input {
+		t.Errorf("marshal is not the same as input:\n%s", got)
+	}
+}
+
+func TestMarshalDesc(t *testing.T) {
+	p, err := readDatabasePkgInfo(strings.NewReader(testDescFull))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(p.License) != 2 {
+		t.Errorf("unexpected licenses: %q", p.License)
+	}
+	if got := string(MarshalDesc(p)); got != testDescFull {
+		t.Errorf("marshal is not the same as input:\n%s", got)
+	}
+
+	// Unknown fields, such as those in the local database, are kept.
+	input := testDescFull + "%XDATA%\npkgtype=pkg\n\n%INSTALLDATE%\n1700000001\n\n%REASON%\n1\n\n"
+	p, err = readDatabasePkgInfo(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error with unknown fields: %s", err)
+	}
+	if got := p.Extras["INSTALLDATE"]; len(got) != 1 || got[0] != "1700000001" {
+		t.Errorf("unexpected extras: %q", p.Extras)
+	}
+	if got := string(MarshalDesc(p)); got != input {
+		t.Errorf("marshal is not the same as input:\n%s", got)
+	}
+
+	// A package read from a database is equal to itself after a round trip.
+	q, err := readDatabasePkgInfo(strings.NewReader(string(MarshalDesc(p))))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !p.Equals(q) {
+		t.Errorf("expected packages to be equal after round trip")
+	}
+
+	if desc := string(MarshalDesc(&Package{Name: "empty"})); !strings.Contains(desc, "%BUILDDATE%\n0\n\n") {
+		t.Errorf("expected zero builddate to be written as 0:\n%s", desc)
+	}
+}
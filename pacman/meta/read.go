@@ -50,7 +50,7 @@ func ReadRepo(h errs.Handler, dirpath string) (Packages, error) {
 		return nil, err
 	}
 
-	return Read(h, dirpath, dbpath)
+	return Read(h, dirpath, dbpath, nil)
 }
 
 // Read reads meta packages in dirpath and using the database at dpbath.
@@ -58,8 +58,9 @@ func ReadRepo(h errs.Handler, dirpath string) (Packages, error) {
 // recurse.
 //
 // If no database can be read, the function still continues reading
-// packages from the directory.
-func Read(h errs.Handler, dirpath, dbpath string) (Packages, error) {
+// packages from the directory. If c is not nil, package files are
+// looked up in the cache before they are read; see pacman.ReadDir.
+func Read(h errs.Handler, dirpath, dbpath string, c *pacman.Cache) (Packages, error) {
 	errs.Init(&h)
 
 	mps := make(map[string]*Package)
@@ -81,7 +82,7 @@ func Read(h errs.Handler, dirpath, dbpath string) (Packages, error) {
 	}
 
 	// Read the packages in the repository directory.
-	fspkgs, err := pacman.ReadDir(h, dirpath, dbpath)
+	fspkgs, err := pacman.ReadDir(h, dirpath, dbpath, c)
 	if err != nil {
 		return nil, err
 	}
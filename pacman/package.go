@@ -66,6 +66,13 @@ const (
 	// 	Description
 	// 	URL
 	// 	License
+	// 	Groups
+	// 	Replaces
+	// 	Provides
+	// 	Conflicts
+	// 	Depends
+	// 	MakeDepends
+	// 	OptionalDepends
 	AUROrigin
 )
 
@@ -90,9 +97,13 @@ type Package struct {
 	URL             string    // url
 	BuildDate       time.Time // builddate
 	Packager        string    // packager
-	Size            uint64    // size
+	Size            uint64    // size (package file) or csize (database)
+	InstalledSize   uint64    // size (package file) or isize (database)
+	MD5Sum          string    // md5sum (database only)
+	SHA256Sum       string    // sha256sum (database only)
+	PGPSignature    string    // pgpsig (database only), base64 encoded
 	Arch            string    // arch: one of any, i686, or x86_64
-	License         string    // license
+	License         []string  // license
 	Backups         []string  // backup
 	Replaces        []string  // replaces
 	Provides        []string  // provides
@@ -103,7 +114,18 @@ type Package struct {
 	MakeDepends     []string  // makedepend
 	CheckDepends    []string  // checkdepend
 	MakeOptions     []string  // makepkgopt
-	Xdata           []string  // xdata
+	Xdata           []string  // xdata, such as "pkgtype=pkg"
+
+	// Comments contains the comment lines at the start of a .PKGINFO,
+	// such as "# Generated by makepkg 6.0.2", so that they can be
+	// written again by MarshalPkgInfo.
+	Comments []string
+
+	// Extras contains the fields that are not known to us, by key as it
+	// appears in the source, such as "installdate" in .PKGINFO or
+	// "INSTALLDATE" in a database desc file. This way, fields that are
+	// added by newer versions of pacman are not lost.
+	Extras map[string][]string
 }
 
 func (p *Package) Pkg() *Package            { return p }
@@ -152,10 +174,19 @@ func (p *Package) Equals(a *Package) bool {
 	if p.Size != a.Size {
 		return false
 	}
+	if p.InstalledSize != a.InstalledSize {
+		return false
+	}
+	if p.MD5Sum != a.MD5Sum || p.SHA256Sum != a.SHA256Sum {
+		return false
+	}
+	if p.PGPSignature != a.PGPSignature {
+		return false
+	}
 	if p.Arch != a.Arch {
 		return false
 	}
-	if p.License != a.License {
+	if !isequalset(p.License, a.License) {
 		return false
 	}
 	if !isequalset(p.Backups, a.Backups) {
@@ -191,6 +222,14 @@ func (p *Package) Equals(a *Package) bool {
 	if !isequalset(p.Xdata, a.Xdata) {
 		return false
 	}
+	if len(p.Extras) != len(a.Extras) {
+		return false
+	}
+	for k, v := range p.Extras {
+		if !isequalset(v, a.Extras[k]) {
+			return false
+		}
+	}
 
 	return true
 }
@@ -215,6 +254,12 @@ func (pkg *Package) Newer(alt *Package) bool {
 	return alpm.VerCmp(pkg.Version, alt.Version) == 1
 }
 
+// Satisfies returns whether the package satisfies the dependency d,
+// either by itself or by one of the packages that it provides.
+func (pkg *Package) Satisfies(d alpm.Depend) bool {
+	return d.SatisfiedBy(pkg.Name, pkg.Version, pkg.Provides)
+}
+
 func isequalset(a, b []string) bool {
 	if &a == &b || (len(a) == 0 && len(b) == 0) {
 		return true
@@ -0,0 +1,264 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package pgp verifies the OpenPGP signatures of packages and databases
+// against a keyring of trusted keys.
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+var (
+	// ErrNoSignature is returned when there is no signature to verify.
+	ErrNoSignature = errors.New("signature missing")
+)
+
+// UntrustedError is returned when a signature was made by a key that is
+// not in the keyring.
+type UntrustedError struct {
+	Filepath string
+}
+
+func (e *UntrustedError) Error() string {
+	return fmt.Sprintf("signature of %q is from an untrusted key", e.Filepath)
+}
+
+// InvalidError is returned when a signature does not match the file, or
+// cannot be read.
+type InvalidError struct {
+	Filepath string
+	Err      error
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("signature of %q is invalid: %s", e.Filepath, e.Err)
+}
+
+func (e *InvalidError) Unwrap() error { return e.Err }
+
+// Key is a trusted public key in a keyring.
+type Key struct {
+	// Fingerprint is the upper-case hexadecimal fingerprint of the
+	// primary key, as shown by gpg.
+	Fingerprint string
+	// UserIDs contains all the identities of the key, such as
+	// "Ben Morgan <cassava@iexu.de>".
+	UserIDs []string
+	// Created is the time the primary key was created.
+	Created time.Time
+
+	entity *openpgp.Entity
+}
+
+func newKey(e *openpgp.Entity) *Key {
+	k := &Key{
+		Fingerprint: fingerprint(e),
+		Created:     e.PrimaryKey.CreationTime,
+		entity:      e,
+	}
+	for name := range e.Identities {
+		k.UserIDs = append(k.UserIDs, name)
+	}
+	sort.Strings(k.UserIDs)
+	return k
+}
+
+func fingerprint(e *openpgp.Entity) string {
+	return strings.ToUpper(fmt.Sprintf("%x", e.PrimaryKey.Fingerprint))
+}
+
+// String returns the fingerprint and the primary identity of the key.
+func (k *Key) String() string {
+	if len(k.UserIDs) == 0 {
+		return k.Fingerprint
+	}
+	return fmt.Sprintf("%s %s", k.Fingerprint, k.UserIDs[0])
+}
+
+// Matches returns true if s identifies the key, either by its full
+// fingerprint or by a key ID, which is a suffix of the fingerprint.
+// An optional "0x" prefix and spaces are ignored.
+func (k *Key) Matches(s string) bool {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimPrefix(s, "0X")
+	if len(s) < 8 {
+		return false
+	}
+	return strings.HasSuffix(k.Fingerprint, s)
+}
+
+// Keyring is a set of trusted public keys that is stored in a file.
+type Keyring struct {
+	path     string
+	entities openpgp.EntityList
+}
+
+// ReadKeyring reads the keyring at the given path. The keyring may be
+// armored or binary, as exported by gpg. If the file does not exist,
+// an empty keyring is returned, which can be written with Write.
+func ReadKeyring(path string) (*Keyring, error) {
+	k := &Keyring{path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return k, nil
+		}
+		return nil, fmt.Errorf("read keyring %s: %w", path, err)
+	}
+	defer f.Close()
+
+	k.entities, err = readKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("read keyring %s: %w", path, err)
+	}
+	return k, nil
+}
+
+// readKeys reads all public keys from r, which may be armored or not.
+func readKeys(r io.Reader) (openpgp.EntityList, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(64)
+	if len(bytes.TrimSpace(head)) == 0 {
+		return nil, nil
+	}
+	if bytes.Contains(head, []byte("-----BEGIN")) {
+		return openpgp.ReadArmoredKeyRing(br)
+	}
+	return openpgp.ReadKeyRing(br)
+}
+
+// Path returns the path of the keyring file.
+func (k *Keyring) Path() string { return k.path }
+
+// Len returns the number of keys in the keyring.
+func (k *Keyring) Len() int { return len(k.entities) }
+
+// Keys returns all keys in the keyring, sorted by fingerprint.
+func (k *Keyring) Keys() []*Key {
+	keys := make([]*Key, len(k.entities))
+	for i, e := range k.entities {
+		keys[i] = newKey(e)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Fingerprint < keys[j].Fingerprint })
+	return keys
+}
+
+// Import reads public keys from r and adds them to the keyring, returning
+// the keys that were added. Keys that are already in the keyring are
+// replaced. Private key material is never stored.
+func (k *Keyring) Import(r io.Reader) ([]*Key, error) {
+	el, err := readKeys(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(el) == 0 {
+		return nil, errors.New("no keys found")
+	}
+
+	var added []*Key
+	for _, e := range el {
+		k.remove(fingerprint(e))
+		k.entities = append(k.entities, e)
+		added = append(added, newKey(e))
+	}
+	return added, nil
+}
+
+// Remove removes all keys that match s, see Key.Matches, and returns them.
+func (k *Keyring) Remove(s string) []*Key {
+	var removed []*Key
+	for _, key := range k.Keys() {
+		if key.Matches(s) {
+			k.remove(key.Fingerprint)
+			removed = append(removed, key)
+		}
+	}
+	return removed
+}
+
+func (k *Keyring) remove(fpr string) {
+	el := k.entities[:0]
+	for _, e := range k.entities {
+		if fingerprint(e) != fpr {
+			el = append(el, e)
+		}
+	}
+	k.entities = el
+}
+
+// Write writes the keyring armored to its path, creating any directories
+// that are required.
+func (k *Keyring) Write() (err error) {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if len(k.entities) != 0 {
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			return err
+		}
+		for _, e := range k.entities {
+			if err := e.Serialize(w); err != nil {
+				return err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(k.path, buf.Bytes(), 0644)
+}
+
+// Verify verifies the detached signature sig of the data in signed, and
+// returns the key that made the signature.
+//
+// If the signature was made by a key not in the keyring, UntrustedError
+// is returned. If the signature is otherwise not valid, InvalidError is
+// returned. The name is only used for these errors.
+func (k *Keyring) Verify(name string, signed, sig io.Reader) (*Key, error) {
+	signer, err := openpgp.CheckDetachedSignature(k.entities, signed, sig, nil)
+	if err != nil {
+		if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+			return nil, &UntrustedError{name}
+		}
+		return nil, &InvalidError{name, err}
+	}
+	return newKey(signer), nil
+}
+
+// VerifyFile verifies the signature of the file at path, which is expected
+// to be at path + ".sig". If there is no signature, ErrNoSignature is
+// returned.
+func (k *Keyring) VerifyFile(path string) (*Key, error) {
+	sig, err := os.Open(path + ".sig")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSignature
+		}
+		return nil, err
+	}
+	defer sig.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return k.Verify(path, f, sig)
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func newTestEntity(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return e
+}
+
+func signFile(t *testing.T, e *openpgp.Entity, path string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, e, f, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := os.WriteFile(path+".sig", sig.Bytes(), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	dir := t.TempDir()
+	trusted := newTestEntity(t, "trusted")
+	stranger := newTestEntity(t, "stranger")
+
+	var pub bytes.Buffer
+	if err := trusted.Serialize(&pub); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	path := filepath.Join(dir, "keyrings", "test.asc")
+	kr, err := ReadKeyring(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if kr.Len() != 0 {
+		t.Fatalf("expected empty keyring")
+	}
+	keys, err := kr.Import(&pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(keys) != 1 || keys[0].UserIDs[0] != "trusted <trusted@example.com>" {
+		t.Fatalf("unexpected imported keys: %v", keys)
+	}
+	if err := kr.Write(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	kr, err = ReadKeyring(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if kr.Len() != 1 || kr.Keys()[0].Fingerprint != keys[0].Fingerprint {
+		t.Fatalf("expected keyring to contain %s", keys[0])
+	}
+
+	good := filepath.Join(dir, "good.pkg.tar.zst")
+	os.WriteFile(good, []byte("good package"), 0644)
+	signFile(t, trusted, good)
+	key, err := kr.VerifyFile(good)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if key.Fingerprint != keys[0].Fingerprint {
+		t.Errorf("expected signer %s, got %s", keys[0].Fingerprint, key.Fingerprint)
+	}
+
+	// Modifying the file after signing must invalidate the signature.
+	os.WriteFile(good, []byte("evil package"), 0644)
+	var invalid *InvalidError
+	if _, err := kr.VerifyFile(good); !errors.As(err, &invalid) {
+		t.Errorf("expected InvalidError, got %v", err)
+	}
+
+	other := filepath.Join(dir, "other.pkg.tar.zst")
+	os.WriteFile(other, []byte("other package"), 0644)
+	signFile(t, stranger, other)
+	var untrusted *UntrustedError
+	if _, err := kr.VerifyFile(other); !errors.As(err, &untrusted) {
+		t.Errorf("expected UntrustedError, got %v", err)
+	}
+
+	unsigned := filepath.Join(dir, "unsigned.pkg.tar.zst")
+	os.WriteFile(unsigned, []byte("unsigned package"), 0644)
+	if _, err := kr.VerifyFile(unsigned); err != ErrNoSignature {
+		t.Errorf("expected ErrNoSignature, got %v", err)
+	}
+
+	// Keys can be removed by their key ID.
+	if removed := kr.Remove("0x" + keys[0].Fingerprint[24:]); len(removed) != 1 {
+		t.Errorf("expected one key to be removed, got %v", removed)
+	}
+	if kr.Len() != 0 {
+		t.Errorf("expected empty keyring after remove")
+	}
+}
+
+func TestSigner(t *testing.T) {
+	dir := t.TempDir()
+	e := newTestEntity(t, "signer")
+	if err := e.EncryptPrivateKeys([]byte("secret"), nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var priv bytes.Buffer
+	w, err := armor.Encode(&priv, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := e.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w.Close()
+	keypath := filepath.Join(dir, "signing.asc")
+	os.WriteFile(keypath, priv.Bytes(), 0600)
+
+	if _, err := ReadSigner(keypath, nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired, got %v", err)
+	}
+	s, err := ReadSigner(keypath, []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pkg := filepath.Join(dir, "foo.pkg.tar.zst")
+	os.WriteFile(pkg, []byte("foo package"), 0644)
+	if err := s.SignFile(pkg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Importing the signing key only trusts its public part.
+	kr, _ := ReadKeyring(filepath.Join(dir, "keyring.asc"))
+	f, _ := os.Open(keypath)
+	defer f.Close()
+	if _, err := kr.Import(f); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	key, err := kr.VerifyFile(pkg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if key.Fingerprint != s.Key().Fingerprint {
+		t.Errorf("expected signer %s, got %s", s.Key().Fingerprint, key.Fingerprint)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pgp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+var (
+	// ErrNoPrivateKey is returned when a signing key file contains
+	// no private key.
+	ErrNoPrivateKey = errors.New("no private key found")
+	// ErrPassphraseRequired is returned when a signing key is encrypted
+	// but no passphrase is given.
+	ErrPassphraseRequired = errors.New("private key is encrypted, passphrase required")
+)
+
+// Signer creates detached signatures with a private key.
+type Signer struct {
+	entity *openpgp.Entity
+}
+
+// ReadSigner reads the first private key from the file at path, which may
+// be armored or binary, as exported by gpg --export-secret-keys.
+// If the private key is encrypted, it is decrypted with passphrase.
+func ReadSigner(path string, passphrase []byte) (*Signer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key %s: %w", path, err)
+	}
+	defer f.Close()
+
+	el, err := readKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key %s: %w", path, err)
+	}
+	for _, e := range el {
+		if e.PrivateKey == nil {
+			continue
+		}
+		if e.PrivateKey.Encrypted {
+			if len(passphrase) == 0 {
+				return nil, fmt.Errorf("read signing key %s: %w", path, ErrPassphraseRequired)
+			}
+			if err := e.DecryptPrivateKeys(passphrase); err != nil {
+				return nil, fmt.Errorf("read signing key %s: %w", path, err)
+			}
+		}
+		return &Signer{e}, nil
+	}
+	return nil, fmt.Errorf("read signing key %s: %w", path, ErrNoPrivateKey)
+}
+
+// Key returns the public part of the signing key.
+func (s *Signer) Key() *Key { return newKey(s.entity) }
+
+// Sign writes a binary detached signature of the data in r to w.
+func (s *Signer) Sign(w io.Writer, r io.Reader) error {
+	return openpgp.DetachSign(w, s.entity, r, nil)
+}
+
+// SignFile creates the detached signature path + ".sig" for the file at
+// path, replacing any signature that is already there.
+func (s *Signer) SignFile(path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sigpath := path + ".sig"
+	tmppath := sigpath + ".tmp"
+	w, err := os.Create(tmppath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmppath)
+		}
+	}()
+	err = s.Sign(w, f)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("sign %s: %w", path, err)
+	}
+	return os.Rename(tmppath, sigpath)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildInfo contains the information in the .BUILDINFO file of a package,
+// which describes the environment that the package was built in.
+type BuildInfo struct {
+	Format            int       // format
+	Name              string    // pkgname
+	Base              string    // pkgbase
+	Version           string    // pkgver
+	Arch              string    // pkgarch
+	PKGBUILDSHA256Sum string    // pkgbuild_sha256sum
+	Packager          string    // packager
+	BuildDate         time.Time // builddate
+	BuildDir          string    // builddir
+	StartDir          string    // startdir
+	BuildTool         string    // buildtool
+	BuildToolVersion  string    // buildtoolver
+	BuildEnv          []string  // buildenv
+	Options           []string  // options
+	// Installed contains the packages that were installed during the build,
+	// in the form name-version-arch.
+	Installed []string // installed
+}
+
+// readBuildInfo reads the contents of a .BUILDINFO file. Unknown fields
+// are ignored, since the format is versioned and may grow over time.
+func readBuildInfo(r io.Reader) (*BuildInfo, error) {
+	var info BuildInfo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, " = ", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid line '%s' in .BUILDINFO", line)
+		}
+
+		var err error
+		switch kv[0] {
+		case "format":
+			info.Format, err = strconv.Atoi(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse format value '%s'", kv[1])
+			}
+		case "pkgname":
+			info.Name = kv[1]
+		case "pkgbase":
+			info.Base = kv[1]
+		case "pkgver":
+			info.Version = kv[1]
+		case "pkgarch":
+			info.Arch = kv[1]
+		case "pkgbuild_sha256sum":
+			info.PKGBUILDSHA256Sum = kv[1]
+		case "packager":
+			info.Packager = kv[1]
+		case "builddate":
+			n, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse build time '%s'", kv[1])
+			}
+			info.BuildDate = time.Unix(n, 0)
+		case "builddir":
+			info.BuildDir = kv[1]
+		case "startdir":
+			info.StartDir = kv[1]
+		case "buildtool":
+			info.BuildTool = kv[1]
+		case "buildtoolver":
+			info.BuildToolVersion = kv[1]
+		case "buildenv":
+			info.BuildEnv = append(info.BuildEnv, kv[1])
+		case "options":
+			info.Options = append(info.Options, kv[1])
+		case "installed":
+			info.Installed = append(info.Installed, kv[1])
+		default:
+			debugf("Ignoring unknown field '%s' in .BUILDINFO\n", kv[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
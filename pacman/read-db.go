@@ -9,31 +9,15 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/goulash/archive"
-	"github.com/goulash/errs"
 	"github.com/goulash/osutil"
 )
 
-// PacmanConfPath contains the path to the pacman configuration.
-// This is provided for those with special needs, such as me ;)
-var PacmanConfPath = "/etc/pacman.conf"
-
-// PacmanLocalDatabasePath contains the path to the local pacman library.
-// This is provided for those with special needs to modify at their own risk.
-var PacmanLocalDatabasePath = "/var/lib/pacman/local"
-
-// PacmanSyncDatabaseFormat is the format that fmt.Sprinf needs to interpolate
-// a repository name into a package database path.
-// This is provided for those with special needs to modify at their own risk.
-var PacmanSyncDatabaseFormat = "/var/lib/pacman/sync/%s.db"
-
 // IsDatabaseLocked returns whether the database given at the path
 // is currently locked for writing or not.
 func IsDatabaseLocked(dbpath string) bool {
@@ -99,133 +83,104 @@ func readTarredDatabasePkgInfo(r io.Reader, dbpath string) (*Package, error) {
 	return pkg, nil
 }
 
-// ReadSyncDatabase reads one of the package databases synced by pacman,
-// such as "core", "extra", "community", and so on.
-//
-// It also reads "/etc/pacman.conf" to make sure that the repository is enabled.
-func ReadSyncDatabase(name string) (Packages, error) {
-	ok, err := IsRepositoryEnabled(name)
-	if err != nil {
-		return nil, fmt.Errorf("cannot determine if repository is enabled: %s", err)
-	}
-	if !ok {
-		return nil, fmt.Errorf("repository %q is not enabled in %s", name, PacmanConfPath)
-	}
+// PackageFiles is a package entry in a files database, together with the
+// list of files that the package contains.
+type PackageFiles struct {
+	*Package
 
-	return ReadDatabase(fmt.Sprintf(PacmanSyncDatabaseFormat, name))
+	// Files contains the paths of all files and directories in the package,
+	// without leading slash. Directories end with a slash.
+	Files []string
 }
 
-// ReadAllSyncDatabases reads all locally synced databases, using /etc/pacman.conf
-// to determine which ones to read.
-func ReadAllSyncDatabases() (Packages, error) {
-	enabled, err := EnabledRepositories()
-	if err != nil {
-		return nil, err
-	}
-
-	// As of October 2017, the main repositories have in total less than 10,000 entries.
-	// I expect this value to rise over time, so reserving space for 15,000 should be
-	// enough for the next few years, hopefully.
-	list := make(Packages, 0, 15000)
-	for _, name := range enabled {
-		pkgs, err := ReadDatabase(fmt.Sprintf(PacmanSyncDatabaseFormat, name))
-		if err != nil {
-			return nil, err
+// Owns returns whether the package contains the file at the given path.
+// Leading slashes are ignored. If the path does not contain a slash, then
+// it is compared against the basename of each file, which is how pacman -F
+// works.
+func (p *PackageFiles) Owns(filepath string) bool {
+	filepath = strings.TrimLeft(filepath, "/")
+	base := !strings.Contains(filepath, "/")
+	for _, f := range p.Files {
+		if f == filepath {
+			return true
+		}
+		if base && !strings.HasSuffix(f, "/") && path.Base(f) == filepath {
+			return true
 		}
-		list = append(list, pkgs...)
 	}
-	return list, nil
+	return false
 }
 
-// IsRepositoryEnabled returns whether the repository named is enabled.
-func IsRepositoryEnabled(name string) (bool, error) {
-	enabled, err := EnabledRepositories()
-	if err != nil {
-		return false, err
-	}
+// ReadFilesDatabase reads all the packages and their file lists from a
+// files database, such as the one that FilesDatabasePath returns.
+func ReadFilesDatabase(dbpath string) ([]*PackageFiles, error) {
+	debugf("Read files database %s\n", dbpath)
 
-	for _, repo := range enabled {
-		if name == repo {
-			return true, nil
+	if ex, err := osutil.FileExists(dbpath); !ex {
+		if err != nil {
+			return nil, fmt.Errorf("read database %s: %w", dbpath, err)
 		}
+		return nil, fmt.Errorf("read database %s: no such file", dbpath)
 	}
-	return false, nil
-}
 
-// EnabledRepositories returns a list of repository names that are enabled
-// in the `/etc/pacman.conf` system configuration file.
-func EnabledRepositories() ([]string, error) {
-	f, err := os.Open(PacmanConfPath)
+	dr, err := archive.NewDecompressor(dbpath)
 	if err != nil {
-		return nil, fmt.Errorf("cannot open %s: %s", PacmanConfPath, err)
+		return nil, fmt.Errorf("read database %s: %w", dbpath, err)
 	}
+	defer dr.Close()
 
-	repos := make([]string, 0, 5)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
-			continue
-		}
-
-		name := line[1 : len(line)-1]
-		if name == "options" {
-			continue
-		}
-
-		repos = append(repos, name)
-	}
-	return repos, nil
-}
+	tr := tar.NewReader(dr)
+	var pkgs []*PackageFiles
 
-// ReadLocalDatabase reads the database of locally installed packages.
-//
-// Note: Even if an error occurs, all successfully read packages will
-// be returned.
-//
-// Note: Errors that occur are passed to the error handler eh, and it is
-// highly recommended that eh always return nil; else reading the local
-// database will be aborted during reading.
-func ReadLocalDatabase(eh errs.Handler) (Packages, error) {
-	var pkgs Packages
-	err := filepath.Walk(PacmanLocalDatabasePath, func(p string, fi os.FileInfo, err error) error {
-		if fi.Name() != "desc" || fi.IsDir() {
-			return nil
+	hdr, err := tr.Next()
+	for hdr != nil {
+		fi := hdr.FileInfo()
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("read database %s: unexpected file '%s'", dbpath, hdr.Name)
 		}
 
-		f, err := os.Open(p)
+		pr := archive.DirReader(tr, &hdr)
+		pkg, files, err := readDatabaseEntry(pr)
 		if err != nil {
-			return eh(fmt.Errorf("%s: %s", p, err))
+			if err == archive.EOA {
+				break
+			}
+			return nil, fmt.Errorf("read database %s: %w", dbpath, err)
 		}
-		defer f.Close()
-		pkg, err := readDatabasePkgInfo(f)
-		if err != nil {
-			return eh(fmt.Errorf("%s: %s", p, err))
+		pkg.Origin = DatabaseOrigin
+		if pkg.Filename != "" {
+			pkg.Filename = path.Join(path.Dir(dbpath), pkg.Filename)
 		}
 
-		// Everything ok
-		pkg.Origin = LocalOrigin
-		pkg.Filename = path.Dir(p)
-		pkgs = append(pkgs, pkg)
-		return nil
-	})
-	return pkgs, err
+		pkgs = append(pkgs, &PackageFiles{Package: pkg, Files: files})
+	}
+
+	return pkgs, nil
 }
 
 func readDatabasePkgInfo(r io.Reader) (*Package, error) {
+	pkg, _, err := readDatabaseEntry(r)
+	return pkg, err
+}
+
+// readDatabaseEntry reads a database entry, which may also contain
+// a list of files, as is the case in a files database.
+func readDatabaseEntry(r io.Reader) (*Package, []string, error) {
 	var err error
+	var files []string
 
 	info := Package{}
 	del := "%"
-	state := ""
+	key, state := "", ""
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
-		if strings.HasPrefix(line, del) && strings.HasSuffix(line, del) {
-			state = strings.ToLower(strings.Trim(line, del))
+		if len(line) > 2 && strings.HasPrefix(line, del) && strings.HasSuffix(line, del) {
+			key = strings.Trim(line, del)
+			state = strings.ToLower(key)
 			continue
 		}
 
@@ -245,7 +200,7 @@ func readDatabasePkgInfo(r io.Reader) (*Package, error) {
 		case "builddate":
 			n, err := strconv.ParseInt(line, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse build time '%s'\n", line)
+				return nil, nil, fmt.Errorf("cannot parse build time '%s'\n", line)
 			}
 			info.BuildDate = time.Unix(n, 0)
 		case "packager":
@@ -253,12 +208,12 @@ func readDatabasePkgInfo(r io.Reader) (*Package, error) {
 		case "csize":
 			info.Size, err = strconv.ParseUint(line, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse size value '%s'\n", line)
+				return nil, nil, fmt.Errorf("cannot parse size value '%s'\n", line)
 			}
 		case "arch":
 			info.Arch = line
 		case "license":
-			info.License = line
+			info.License = append(info.License, line)
 		case "depends":
 			info.Depends = append(info.Depends, line)
 		case "optdepends":
@@ -278,20 +233,35 @@ func readDatabasePkgInfo(r io.Reader) (*Package, error) {
 		case "groups":
 			info.Groups = append(info.Groups, line)
 		case "xdata":
-			info.Xdata = append(info.Xdata, strings.Split(line, " ")...)
-		case "isize", "md5sum", "pgpsig", "sha256sum":
-			// We ignore these fields for now...
-			continue
-		case "installdate", "size", "validation", "reason":
-			// These fields are in the local database desc file and we don't care about them
-			continue
+			info.Xdata = append(info.Xdata, line)
+		case "files":
+			files = append(files, line)
+		case "isize":
+			info.InstalledSize, err = strconv.ParseUint(line, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot parse size value '%s'\n", line)
+			}
+		case "md5sum":
+			info.MD5Sum = line
+		case "sha256sum":
+			info.SHA256Sum = line
+		case "pgpsig":
+			info.PGPSignature = line
+		case "":
+			return nil, nil, fmt.Errorf("unexpected value '%s' before first field in database entry", line)
 		default:
-			return nil, fmt.Errorf("unknown field '%s' in database entry\n", state)
+			// This includes fields such as %INSTALLDATE% from the local
+			// database, which we don't need, but also fields that are newer
+			// than this code.
+			if info.Extras == nil {
+				info.Extras = make(map[string][]string)
+			}
+			info.Extras[key] = append(info.Extras[key], line)
 		}
 	}
 	if err = scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &info, nil
+	return &info, files, nil
 }
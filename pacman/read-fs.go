@@ -9,6 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/cassava/repoctl/pacman/alpm"
 	"github.com/goulash/errs"
@@ -16,39 +19,44 @@ import (
 
 // ReadDir reads all packages that are found in the repository
 // directory.
-func ReadDir(h errs.Handler, dirpath, dbpath string) (Packages, error) {
+//
+// A package file is only read if it is not in the database at dbpath
+// or is newer than the database, and it is not in the cache c either.
+// Packages that are read are added to the cache, which is written
+// afterwards. If c is nil, no cache is used.
+func ReadDir(h errs.Handler, dirpath, dbpath string, c *Cache) (Packages, error) {
 	errs.Init(&h)
 
-	// 1. First make sure we can open the database before we do some
-	// complicated logic.
-	dirpath = filepath.Clean(dirpath)
-	dbinfo, err := os.Stat(dbpath)
-	if err != nil {
-		return ReadEveryFileInDir(h, dirpath)
-	}
-
-	// 2. Read a list of packages from the database.
+	// 1. Read a list of packages from the database, if we can.
 	// Fix the filenames of each of packages and then put them in a set.
-	dbPkgs, err := ReadDatabase(dbpath)
-	if err != nil {
-		return ReadEveryFileInDir(h, dirpath)
-	}
+	// If there is no database, then dbtime is zero and we ignore it.
+	dirpath = filepath.Clean(dirpath)
+	var dbtime time.Time
 	pkgs := make(map[string]*Package)
-	for _, p := range dbPkgs {
-		pkgpath := filepath.Join(dirpath, filepath.Base(p.Filename))
-		pkgs[pkgpath] = p
+	if dbinfo, err := os.Stat(dbpath); err == nil {
+		dbPkgs, err := ReadDatabase(dbpath)
+		if err == nil {
+			dbtime = dbinfo.ModTime()
+			for _, p := range dbPkgs {
+				pkgpath := filepath.Join(dirpath, filepath.Base(p.Filename))
+				pkgs[pkgpath] = p
+			}
+		}
 	}
 
-	// 3. Get the list of packages in the directory, and cross-check with the
-	// entries from the database to see which ones we need to read. At each
-	// entry we add it to the results list. This gives us the ordering we
-	// would have anyway from reading the directory, but we don't have to
-	// open each file.
-	dbtime := dbinfo.ModTime()
+	// 2. Get the list of packages in the directory, and cross-check with the
+	// entries from the database and the cache to see which ones we need to
+	// read. At each entry we add it to the results list. This gives us the
+	// ordering we would have anyway from reading the directory, but we don't
+	// have to open each file.
 	results := make(Packages, 0, len(pkgs))
-	err = filepath.Walk(dirpath, func(filename string, info os.FileInfo, err error) error {
+	seen := make(map[string]bool)
+	var unread []string
+	var infos []os.FileInfo
+	var indices []int
+	err := filepath.Walk(dirpath, func(filename string, info os.FileInfo, err error) error {
 		if err != nil {
-			return h(err)
+			return h(fmt.Errorf("read file %s: %w", filename, err))
 		}
 		if info.Mode().IsDir() {
 			if filename == dirpath {
@@ -57,6 +65,8 @@ func ReadDir(h errs.Handler, dirpath, dbpath string) (Packages, error) {
 			return filepath.SkipDir
 		}
 		if alpm.HasPackageFormat(filename) {
+			seen[filename] = true
+
 			// If there is an entry in the database AND the database is newer
 			// than the file, then we use the database entry and continue to
 			// the next file.
@@ -66,30 +76,68 @@ func ReadDir(h errs.Handler, dirpath, dbpath string) (Packages, error) {
 					return nil
 				}
 			}
+			if p, ok := c.Lookup(filename, info); ok {
+				results = append(results, p)
+				return nil
+			}
 
 			// Either the file doesn't exist or dbtime is older than this file.
-			p, err := Read(filename)
-			if err != nil {
-				return h(err)
-			}
-			results = append(results, p)
+			// We remember where it goes and read it later with the others.
+			unread = append(unread, filename)
+			infos = append(infos, info)
+			indices = append(indices, len(results))
+			results = append(results, nil)
 		}
 		return nil
 	})
+	if err != nil {
+		return compact(results), err
+	}
+
+	read, errors := readFiles(unread)
+	for i, p := range read {
+		if errors[i] != nil {
+			if err = h(errors[i]); err != nil {
+				break
+			}
+			continue
+		}
+		c.Store(unread[i], infos[i], p)
+		results[indices[i]] = p
+	}
+
+	// Files that no longer exist are dropped from the cache, so that
+	// it does not grow forever. The cache is only an optimization, so
+	// failing to write it is not an error.
+	c.Prune(dirpath, func(filename string) bool { return seen[filename] })
+	if werr := c.Write(); werr != nil {
+		debugf("Warning: %s\n", werr)
+	}
 
 	// Much faster.
-	return results, err
+	return compact(results), err
+}
+
+// compact returns pkgs without the nil entries.
+func compact(pkgs Packages) Packages {
+	n := 0
+	for _, p := range pkgs {
+		if p != nil {
+			pkgs[n] = p
+			n++
+		}
+	}
+	return pkgs[:n]
 }
 
 // ReadEveryFileInDir reads all the packages it finds in a directory.
 //
-// Note: this will try to read each package, and currently it does this
-// in an inefficient manner, decompressing the entire package in memory.
-// Even a directory with only a few files will take a while to process.
+// Only the .PKGINFO of each package is decompressed, and the packages are
+// read in parallel. Even so, this is much slower than reading a database.
 func ReadEveryFileInDir(h errs.Handler, dirpath string) (Packages, error) {
 	errs.Init(&h)
 
-	var pkgs Packages
+	var files []string
 	dirpath = filepath.Clean(dirpath)
 	err := filepath.Walk(dirpath, func(filename string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -102,24 +150,23 @@ func ReadEveryFileInDir(h errs.Handler, dirpath string) (Packages, error) {
 			return filepath.SkipDir
 		}
 		if alpm.HasPackageFormat(filename) {
-			p, err := Read(filename)
-			if err != nil {
-				return h(err)
-			}
-
-			pkgs = append(pkgs, p)
+			files = append(files, filename)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return pkgs, err
+	return ReadFiles(h, files...)
 }
 
 // ReadDirApproxOnlyNames returns the names of all packages it finds
 // in a directory.
 //
-// This does not have the performance issues that ReadEveryFileInDir has.
+// This only looks at the filenames, so it is much faster than
+// ReadEveryFileInDir.
 func ReadDirApproxOnlyNames(h errs.Handler, dirpath string) ([]string, error) {
 	errs.Init(&h)
 	re := regexp.MustCompile(alpm.PackageRegex)
@@ -149,41 +196,72 @@ func ReadDirApproxOnlyNames(h errs.Handler, dirpath string) ([]string, error) {
 }
 
 // ReadFiles reads all the given package files.
+//
+// The files are read in parallel, but the packages are returned in the
+// order of the files given, and errors are passed to h in that order.
+// If h returns an error, the packages read until then are returned.
 func ReadFiles(h errs.Handler, pkgfiles ...string) (Packages, error) {
 	errs.Init(&h)
 	if len(pkgfiles) == 0 {
 		return nil, nil
 	}
 
+	read, errors := readFiles(pkgfiles)
 	pkgs := make(Packages, 0, len(pkgfiles))
-	for _, pf := range pkgfiles {
-		p, err := Read(pf)
-		if err != nil {
-			err = h(err)
-			if err != nil {
+	for i, p := range read {
+		if errors[i] != nil {
+			if err := h(errors[i]); err != nil {
 				return pkgs, err
 			}
-		} else {
-			pkgs = append(pkgs, p)
+			continue
 		}
+		pkgs = append(pkgs, p)
 	}
 	return pkgs, nil
 }
 
+// readFiles reads the given package files with a bounded number of
+// workers. For each file, either the package or the error is returned
+// at the same index.
+func readFiles(pkgfiles []string) (Packages, []error) {
+	pkgs := make(Packages, len(pkgfiles))
+	errors := make([]error, len(pkgfiles))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(pkgfiles) {
+		workers = len(pkgfiles)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				pkgs[i], errors[i] = Read(pkgfiles[i])
+			}
+		}()
+	}
+	for i := range pkgfiles {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return pkgs, errors
+}
+
 // ReadNames reads all packages with one of the given names in a directory.
 func ReadNames(h errs.Handler, dirpath string, pkgnames ...string) (Packages, error) {
 	errs.Init(&h)
 
-	var pkgs Packages
-	var err error
-
+	var files []string
+	var names []string
 	for _, n := range pkgnames {
-		var matches []string
-		matches, err = filepath.Glob(filepath.Join(dirpath, n+alpm.PackageGlob))
+		matches, err := filepath.Glob(filepath.Join(dirpath, n+alpm.PackageGlob))
 		if err != nil {
 			err = h(fmt.Errorf("cannot find package %q", n))
 			if err != nil {
-				break
+				return nil, err
 			}
 			continue
 		}
@@ -192,20 +270,23 @@ func ReadNames(h errs.Handler, dirpath string, pkgnames ...string) (Packages, er
 				// Globbing also finds signatures, which we currently ignore
 				continue
 			}
-			p, err := Read(fp)
-			if err != nil {
-				err = h(err)
-				if err != nil {
-					break
-				}
-				continue
-			}
+			files = append(files, fp)
+			names = append(names, n)
+		}
+	}
 
-			if p.Name == n {
-				pkgs = append(pkgs, p)
+	var pkgs Packages
+	read, errors := readFiles(files)
+	for i, p := range read {
+		if errors[i] != nil {
+			if err := h(errors[i]); err != nil {
+				return pkgs, err
 			}
+			continue
+		}
+		if p.Name == names[i] {
+			pkgs = append(pkgs, p)
 		}
 	}
-
-	return pkgs, err
+	return pkgs, nil
 }
@@ -0,0 +1,109 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestRepository creates n packages named pkg00, pkg01, and so on in
+// dir, and returns their paths in lexical order.
+func writeTestRepository(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	files := make([]string, n)
+	for i := range files {
+		name := fmt.Sprintf("pkg%02d", i)
+		files[i] = writeTestPackage(t, dir, name+"-1.0-1-any.pkg.tar.gz",
+			fmt.Sprintf("pkgname = %s\npkgver = 1.0-1\narch = any\n", name))
+	}
+	return files
+}
+
+func TestReadEveryFileInDir(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestRepository(t, dir, 40)
+
+	// A corrupt package must be reported through the handler,
+	// without affecting the other packages.
+	broken := filepath.Join(dir, "broken-1.0-1-any.pkg.tar.gz")
+	os.WriteFile(broken, []byte("not a package"), 0644)
+
+	var errors []error
+	pkgs, err := ReadEveryFileInDir(func(err error) error {
+		errors = append(errors, err)
+		return nil
+	}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(errors) != 1 {
+		t.Errorf("expected one error, got %v", errors)
+	}
+	if len(pkgs) != len(files) {
+		t.Fatalf("expected %d packages, got %d", len(files), len(pkgs))
+	}
+	for i, p := range pkgs {
+		if p.Filename != files[i] {
+			t.Errorf("expected package %d to be %s, got %s", i, files[i], p.Filename)
+		}
+	}
+
+	// Aborting in the handler stops reading.
+	_, err = ReadEveryFileInDir(func(err error) error { return err }, dir)
+	if err == nil {
+		t.Errorf("expected error from handler")
+	}
+}
+
+func TestReadDirWithDatabase(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestRepository(t, dir, 10)
+	dbpath := filepath.Join(dir, "test.db.tar.gz")
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, f := range files[:5] {
+		db.Add(f)
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pkgs, err := ReadDir(nil, dir, dbpath, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != len(files) {
+		t.Fatalf("expected %d packages, got %d", len(files), len(pkgs))
+	}
+	for i, p := range pkgs {
+		if p.Filename != files[i] {
+			t.Errorf("expected package %d to be %s, got %s", i, files[i], p.Filename)
+		}
+		if i < 5 && dbNewer(dbpath, files[i]) && p.Origin != DatabaseOrigin {
+			t.Errorf("expected package %d from database, got origin %v", i, p.Origin)
+		}
+	}
+
+	names, err := ReadNames(nil, dir, "pkg03", "pkg07", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(names) != 2 || names[0].Name != "pkg03" || names[1].Name != "pkg07" {
+		t.Errorf("unexpected result from ReadNames: %v", names)
+	}
+}
+
+// dbNewer returns true if the database is newer than the file, which is
+// when ReadDir uses the database entry.
+func dbNewer(dbpath, file string) bool {
+	a, _ := os.Stat(dbpath)
+	b, _ := os.Stat(file)
+	return a.ModTime().After(b.ModTime())
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MtreeEntry is an entry in the .MTREE file of a package, which describes
+// a file in the package, including its checksums.
+type MtreeEntry struct {
+	// Path is the path of the file without leading "./".
+	Path string
+	// Type is one of file, dir, or link.
+	Type         string
+	Mode         os.FileMode
+	UID          int
+	GID          int
+	Size         int64
+	Time         time.Time
+	Link         string
+	MD5Digest    string
+	SHA256Digest string
+}
+
+// readMtree reads the contents of a .MTREE file, which may be compressed
+// with gzip, as it is in packages. Entries of metadata files, such as
+// .PKGINFO, are included.
+func readMtree(r io.Reader) ([]*MtreeEntry, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	}
+
+	var entries []*MtreeEntry
+	defaults := make(map[string]string)
+	scanner := bufio.NewScanner(br)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "/set":
+			for _, kv := range fields[1:] {
+				if i := strings.IndexByte(kv, '='); i != -1 {
+					defaults[kv[:i]] = kv[i+1:]
+				}
+			}
+			continue
+		case "/unset":
+			for _, k := range fields[1:] {
+				delete(defaults, k)
+			}
+			continue
+		}
+
+		keywords := make(map[string]string, len(defaults)+len(fields))
+		for k, v := range defaults {
+			keywords[k] = v
+		}
+		for _, kv := range fields[1:] {
+			if i := strings.IndexByte(kv, '='); i != -1 {
+				keywords[kv[:i]] = kv[i+1:]
+			}
+		}
+
+		e, err := newMtreeEntry(unescapeMtree(fields[0]), keywords)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry '%s' in .MTREE: %w", fields[0], err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func newMtreeEntry(path string, keywords map[string]string) (*MtreeEntry, error) {
+	e := &MtreeEntry{
+		Path:         strings.TrimPrefix(path, "./"),
+		Type:         keywords["type"],
+		Link:         unescapeMtree(keywords["link"]),
+		MD5Digest:    keywords["md5digest"],
+		SHA256Digest: keywords["sha256digest"],
+	}
+
+	var err error
+	if v, ok := keywords["mode"]; ok {
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse mode '%s'", v)
+		}
+		e.Mode = os.FileMode(mode)
+	}
+	if v, ok := keywords["uid"]; ok {
+		if e.UID, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("cannot parse uid '%s'", v)
+		}
+	}
+	if v, ok := keywords["gid"]; ok {
+		if e.GID, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("cannot parse gid '%s'", v)
+		}
+	}
+	if v, ok := keywords["size"]; ok {
+		if e.Size, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("cannot parse size '%s'", v)
+		}
+	}
+	if v, ok := keywords["time"]; ok {
+		t, err := parseMtreeTime(v)
+		if err != nil {
+			return nil, err
+		}
+		e.Time = t
+	}
+	return e, nil
+}
+
+// parseMtreeTime parses the time keyword of an mtree entry. libarchive
+// writes it with the format "%jd.%jd", so the part after the dot is a
+// count of nanoseconds and not a decimal fraction: "1.5" is one second
+// and five nanoseconds. Counts that do not fit in a second are clamped.
+func parseMtreeTime(v string) (time.Time, error) {
+	sec, nsec, _ := strings.Cut(v, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse time '%s'", v)
+	}
+	var ns int64
+	if nsec != "" {
+		if strings.Trim(nsec, "0123456789") != "" {
+			return time.Time{}, fmt.Errorf("cannot parse time '%s'", v)
+		}
+		ns, err = strconv.ParseInt(nsec, 10, 64)
+		if err != nil || ns > 999999999 {
+			ns = 999999999
+		}
+	}
+	return time.Unix(s, ns), nil
+}
+
+// unescapeMtree replaces the octal escape sequences, such as \040 for
+// a space, that mtree uses in paths.
+func unescapeMtree(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				buf.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
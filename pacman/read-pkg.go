@@ -5,10 +5,11 @@
 package pacman
 
 import (
+	"archive/tar"
 	"bufio"
-	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,15 +21,30 @@ import (
 // and returns it in the Package datatype.
 func Read(filename string) (*Package, error) {
 	debugf("Read package %s\n", filename)
-	bs, err := archive.ReadFileFromArchive(filename, ".PKGINFO")
+	d, err := archive.NewDecompressor(filename)
 	if err != nil {
 		return nil, fmt.Errorf("read package %s: %w", filename, err)
 	}
+	defer d.Close()
 
-	r := bytes.NewReader(bs)
-	info, err := readFilePkgInfo(r)
-	if err != nil {
-		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	// The .PKGINFO is usually at the beginning of the package, so we stop
+	// reading as soon as we find it, instead of decompressing the rest.
+	tr := tar.NewReader(d)
+	var info *Package
+	for info == nil {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("read package %s: cannot find file \".PKGINFO\"", filename)
+		} else if err != nil {
+			return nil, fmt.Errorf("read package %s: %w", filename, err)
+		}
+		if strings.TrimPrefix(hdr.Name, "./") != ".PKGINFO" {
+			continue
+		}
+		info, err = readFilePkgInfo(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read package %s: %w", filename, err)
+		}
 	}
 
 	info.Filename = filename
@@ -36,87 +52,138 @@ func Read(filename string) (*Package, error) {
 	return info, nil
 }
 
+// ReadFileList reads the list of files that a pacman package installs,
+// in the same format as they are stored in a files database: sorted,
+// without leading slash, and with directories ending in a slash.
+// Metadata files such as .PKGINFO and .MTREE are not included.
+func ReadFileList(filename string) ([]string, error) {
+	debugf("Read file list %s\n", filename)
+	d, err := archive.NewDecompressor(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read package %s: %w", filename, err)
+	}
+	defer d.Close()
+
+	var files []string
+	tr := tar.NewReader(d)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read package %s: %w", filename, err)
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // readFilePkgInfo reads the package information from a pacman package.
 //
 // We don't do any specific controlling for you, so you should use
 // HasPackageFormat on a path string before using this function on it.
 // Even if you don't, nothing bad should happen, but just in case.
+//
+// Fields that we do not know are stored in Extras, and the comments before
+// the first field in Comments.
 func readFilePkgInfo(r io.Reader) (*Package, error) {
 	var (
-		info  Package
-		err   error
-		epoch int
+		info   Package
+		err    error
+		epoch  int
+		header = true
 	)
 
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix("#", line) {
+		if header && strings.HasPrefix(line, "#") {
+			info.Comments = append(info.Comments, line)
 			continue
 		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		header = false
 
-		kv := strings.Split(line, " = ")
-		if len(kv) != 2 {
+		// Values may be empty or contain "=" themselves, as in xdata,
+		// but keys never do.
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
 			continue
 		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
 
-		switch kv[0] {
+		switch key {
 		case "pkgname":
-			info.Name = kv[1]
+			info.Name = value
 		case "pkgver":
-			info.Version = kv[1]
+			info.Version = value
 		case "pkgdesc":
-			info.Description = kv[1]
+			info.Description = value
 		case "pkgbase":
-			info.Base = kv[1]
+			info.Base = value
 		case "epoch":
-			epoch, err = strconv.Atoi(kv[1])
+			epoch, err = strconv.Atoi(value)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse epoch value '%s'", kv[1])
+				return nil, fmt.Errorf("cannot parse epoch value '%s'", value)
 			}
 		case "url":
-			info.URL = kv[1]
+			info.URL = value
 		case "builddate":
-			n, err := strconv.ParseInt(kv[1], 10, 64)
+			n, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse build time '%s'", kv[1])
+				return nil, fmt.Errorf("cannot parse build time '%s'", value)
 			}
 			info.BuildDate = time.Unix(n, 0)
 		case "packager":
-			info.Packager = kv[1]
+			info.Packager = value
 		case "size":
-			info.Size, err = strconv.ParseUint(kv[1], 10, 64)
+			info.Size, err = strconv.ParseUint(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse size value '%s'", kv[1])
+				return nil, fmt.Errorf("cannot parse size value '%s'", value)
 			}
+			info.InstalledSize = info.Size
 		case "arch":
-			info.Arch = kv[1]
+			info.Arch = value
 		case "license":
-			info.License = kv[1]
+			info.License = append(info.License, value)
 		case "depend":
-			info.Depends = append(info.Depends, kv[1])
+			info.Depends = append(info.Depends, value)
 		case "optdepend":
-			info.OptionalDepends = append(info.OptionalDepends, kv[1])
+			info.OptionalDepends = append(info.OptionalDepends, value)
 		case "makedepend":
-			info.MakeDepends = append(info.MakeDepends, kv[1])
+			info.MakeDepends = append(info.MakeDepends, value)
 		case "checkdepend":
-			info.CheckDepends = append(info.CheckDepends, kv[1])
+			info.CheckDepends = append(info.CheckDepends, value)
 		case "makepkgopt":
-			info.MakeOptions = append(info.MakeOptions, kv[1])
+			info.MakeOptions = append(info.MakeOptions, value)
 		case "backup":
-			info.Backups = append(info.Backups, kv[1])
+			info.Backups = append(info.Backups, value)
 		case "replaces":
-			info.Replaces = append(info.Replaces, kv[1])
+			info.Replaces = append(info.Replaces, value)
 		case "provides":
-			info.Provides = append(info.Provides, kv[1])
+			info.Provides = append(info.Provides, value)
 		case "conflict":
-			info.Conflicts = append(info.Conflicts, kv[1])
+			info.Conflicts = append(info.Conflicts, value)
 		case "group":
-			info.Groups = append(info.Groups, kv[1])
+			info.Groups = append(info.Groups, value)
 		case "xdata":
-			info.Xdata = append(info.Xdata, strings.Split(kv[1], " ")...)
+			info.Xdata = append(info.Xdata, value)
 		default:
-			return nil, fmt.Errorf("unknown field '%s' in .PKGINFO", kv[0])
+			if info.Extras == nil {
+				info.Extras = make(map[string][]string)
+			}
+			info.Extras[key] = append(info.Extras[key], value)
 		}
 	}
 	if err = scanner.Err(); err != nil {
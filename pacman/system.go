@@ -0,0 +1,257 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/goulash/errs"
+)
+
+// System is a pacman installation, whose configuration and databases
+// are read. This is usually the host system, but it can also be another
+// root, such as a clean chroot or a system of another architecture.
+type System struct {
+	// Root is the root directory of the system. All paths in the pacman
+	// configuration, including those of Include directives, are
+	// interpreted relative to it. If empty, "/" is used.
+	Root string
+	// DBPath overrides the DBPath in the pacman configuration. Unlike the
+	// paths in the configuration, it is not relative to Root.
+	DBPath string
+	// ConfPath is the path to the pacman configuration. If empty,
+	// etc/pacman.conf in Root is used.
+	ConfPath string
+}
+
+// DefaultSystem returns the pacman installation of the host.
+func DefaultSystem() *System {
+	return &System{Root: "/"}
+}
+
+// IsDefault returns true if s refers to the pacman installation of the host.
+func (s *System) IsDefault() bool {
+	return s.root() == "/" && s.DBPath == "" && s.ConfPath == ""
+}
+
+func (s *System) root() string {
+	if s.Root == "" {
+		return "/"
+	}
+	return s.Root
+}
+
+// ConfigPath returns the path to the pacman configuration of the system.
+func (s *System) ConfigPath() string {
+	if s.ConfPath != "" {
+		return s.ConfPath
+	}
+	return filepath.Join(s.root(), "etc", "pacman.conf")
+}
+
+// ReadConfig reads the pacman configuration of the system. The paths
+// RootDir and DBPath in the returned configuration are those on the host.
+func (s *System) ReadConfig() (*Config, error) {
+	c, err := readConfig(s.ConfigPath(), s.root())
+	if err != nil {
+		return nil, err
+	}
+	c.RootDir = filepath.Join(s.root(), c.RootDir)
+	if s.DBPath != "" {
+		c.DBPath = s.DBPath
+	} else {
+		c.DBPath = filepath.Join(s.root(), c.DBPath)
+	}
+	return c, nil
+}
+
+// ReadSyncDatabase reads one of the package databases synced by pacman,
+// such as "core", "extra", "multilib", and so on.
+//
+// It also reads the pacman configuration to make sure that the repository
+// is enabled.
+func (s *System) ReadSyncDatabase(name string) (Packages, error) {
+	c, err := s.ReadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine if repository is enabled: %s", err)
+	}
+	if c.Repository(name) == nil {
+		return nil, fmt.Errorf("repository %q is not enabled in %s", name, s.ConfigPath())
+	}
+
+	return ReadDatabase(c.SyncDatabasePath(name))
+}
+
+// ReadAllSyncDatabases reads all synced databases, using the pacman
+// configuration to determine which ones to read.
+func (s *System) ReadAllSyncDatabases() (Packages, error) {
+	c, err := s.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// As of October 2017, the main repositories have in total less than 10,000 entries.
+	// I expect this value to rise over time, so reserving space for 15,000 should be
+	// enough for the next few years, hopefully.
+	list := make(Packages, 0, 15000)
+	for _, name := range c.RepositoryNames() {
+		pkgs, err := ReadDatabase(c.SyncDatabasePath(name))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, pkgs...)
+	}
+	return list, nil
+}
+
+// IsRepositoryEnabled returns whether the repository named is enabled.
+func (s *System) IsRepositoryEnabled(name string) (bool, error) {
+	c, err := s.ReadConfig()
+	if err != nil {
+		return false, err
+	}
+	return c.Repository(name) != nil, nil
+}
+
+// EnabledRepositories returns a list of repository names that are enabled
+// in the pacman configuration, including any files that it includes.
+func (s *System) EnabledRepositories() ([]string, error) {
+	c, err := s.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return c.RepositoryNames(), nil
+}
+
+// ReadLocalDatabase reads the database of installed packages.
+//
+// Note: Even if an error occurs, all successfully read packages will
+// be returned.
+//
+// Note: Errors that occur are passed to the error handler eh, and it is
+// highly recommended that eh always return nil; else reading the local
+// database will be aborted during reading.
+func (s *System) ReadLocalDatabase(eh errs.Handler) (Packages, error) {
+	c, err := s.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return readLocalDatabase(c.LocalDatabasePath(), eh)
+}
+
+// readLocalDatabase reads the database of installed packages in dir.
+func readLocalDatabase(dir string, eh errs.Handler) (Packages, error) {
+	var pkgs Packages
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return eh(err)
+		}
+		if fi.Name() != "desc" || fi.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return eh(fmt.Errorf("%s: %s", p, err))
+		}
+		defer f.Close()
+		pkg, err := readDatabasePkgInfo(f)
+		if err != nil {
+			return eh(fmt.Errorf("%s: %s", p, err))
+		}
+
+		// Everything ok
+		pkg.Origin = LocalOrigin
+		pkg.Filename = path.Dir(p)
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	return pkgs, err
+}
+
+// PacmanConfPath contains the path to the pacman configuration that is
+// read by the deprecated package-level functions below.
+//
+// Deprecated: Set System.ConfPath instead.
+var PacmanConfPath = "/etc/pacman.conf"
+
+// PacmanLocalDatabasePath contains the path to the local pacman library
+// that is read by the deprecated ReadLocalDatabase.
+//
+// Deprecated: Set System.DBPath or System.Root instead.
+var PacmanLocalDatabasePath = "/var/lib/pacman/local"
+
+// PacmanSyncDatabaseFormat is the format that fmt.Sprintf needs to
+// interpolate the name of a repository into the path of its synced
+// database, which is read by the deprecated package-level functions.
+//
+// Deprecated: Set System.DBPath or System.Root instead.
+var PacmanSyncDatabaseFormat = "/var/lib/pacman/sync/%s.db"
+
+// defaultSystem returns the host system, with PacmanConfPath as its
+// configuration, for the deprecated package-level functions.
+func defaultSystem() *System {
+	s := DefaultSystem()
+	s.ConfPath = PacmanConfPath
+	return s
+}
+
+// ReadSyncDatabase reads one of the package databases synced by pacman.
+//
+// Deprecated: Use DefaultSystem().ReadSyncDatabase instead.
+func ReadSyncDatabase(name string) (Packages, error) {
+	ok, err := defaultSystem().IsRepositoryEnabled(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine if repository is enabled: %s", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("repository %q is not enabled in %s", name, PacmanConfPath)
+	}
+	return ReadDatabase(fmt.Sprintf(PacmanSyncDatabaseFormat, name))
+}
+
+// ReadAllSyncDatabases reads all synced databases of the host.
+//
+// Deprecated: Use DefaultSystem().ReadAllSyncDatabases instead.
+func ReadAllSyncDatabases() (Packages, error) {
+	names, err := defaultSystem().EnabledRepositories()
+	if err != nil {
+		return nil, err
+	}
+	var list Packages
+	for _, name := range names {
+		pkgs, err := ReadDatabase(fmt.Sprintf(PacmanSyncDatabaseFormat, name))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, pkgs...)
+	}
+	return list, nil
+}
+
+// IsRepositoryEnabled returns whether the repository named is enabled.
+//
+// Deprecated: Use DefaultSystem().IsRepositoryEnabled instead.
+func IsRepositoryEnabled(name string) (bool, error) {
+	return defaultSystem().IsRepositoryEnabled(name)
+}
+
+// EnabledRepositories returns a list of repository names that are enabled
+// in the pacman configuration of the host.
+//
+// Deprecated: Use DefaultSystem().EnabledRepositories instead.
+func EnabledRepositories() ([]string, error) {
+	return defaultSystem().EnabledRepositories()
+}
+
+// ReadLocalDatabase reads the database of packages installed on the host.
+//
+// Deprecated: Use DefaultSystem().ReadLocalDatabase instead.
+func ReadLocalDatabase(eh errs.Handler) (Packages, error) {
+	return readLocalDatabase(PacmanLocalDatabasePath, eh)
+}
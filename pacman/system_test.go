@@ -0,0 +1,73 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSystemRoot(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "etc", "pacman.d"), 0755)
+	os.MkdirAll(filepath.Join(root, "var", "lib", "pacman", "local", "bar-2.1-3"), 0755)
+	os.MkdirAll(filepath.Join(root, "var", "lib", "pacman", "sync"), 0755)
+
+	// The Include path is interpreted relative to the root.
+	os.WriteFile(filepath.Join(root, "etc", "pacman.conf"), []byte(`[options]
+Architecture = aarch64
+[core]
+Server = https://example.com/$arch/$repo
+Include = /etc/pacman.d/*.conf
+`), 0644)
+	os.WriteFile(filepath.Join(root, "etc", "pacman.d", "sirius.conf"), []byte("[sirius]\n"), 0644)
+	os.WriteFile(filepath.Join(root, "var", "lib", "pacman", "local", "bar-2.1-3", "desc"),
+		[]byte("%NAME%\nbar\n\n%VERSION%\n2.1-3\n"), 0644)
+
+	foo := writeTestPackage(t, root, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo)
+	db, err := OpenDatabaseWriter(filepath.Join(root, "var", "lib", "pacman", "sync", "core.db.tar.gz"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db.Add(foo)
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sys := &System{Root: root}
+	if sys.IsDefault() || !DefaultSystem().IsDefault() {
+		t.Errorf("unexpected result from IsDefault")
+	}
+	c, err := sys.ReadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(c.RepositoryNames(), []string{"core", "sirius"}) {
+		t.Errorf("unexpected repositories: %v", c.RepositoryNames())
+	}
+	if c.Repository("core").Servers[0] != "https://example.com/aarch64/core" {
+		t.Errorf("unexpected server: %s", c.Repository("core").Servers[0])
+	}
+
+	local, err := sys.ReadLocalDatabase(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(local) != 1 || local[0].Name != "bar" || local[0].Origin != LocalOrigin {
+		t.Errorf("unexpected local packages: %v", local)
+	}
+	pkgs, err := sys.ReadSyncDatabase("core")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "foo" {
+		t.Errorf("unexpected sync packages: %v", pkgs)
+	}
+	if _, err := sys.ReadSyncDatabase("extra"); err == nil {
+		t.Errorf("expected error reading disabled repository")
+	}
+}
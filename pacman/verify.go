@@ -0,0 +1,130 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// IntegrityProblem describes how a package file differs from what the
+// database says about it.
+type IntegrityProblem int
+
+const (
+	// FileMissing means that the package file does not exist.
+	FileMissing IntegrityProblem = iota + 1
+	// FileTruncated means that the package file is smaller than expected.
+	FileTruncated
+	// FileCorrupted means that the size or checksum of the package file
+	// is wrong, and the file is not a readable package.
+	FileCorrupted
+	// FileSwapped means that the package file is a valid package, but not
+	// the one that the database refers to.
+	FileSwapped
+	// SignatureMismatch means that the signature file next to the package
+	// differs from the signature in the database.
+	SignatureMismatch
+	// SignatureMissing means that the database has a signature for the
+	// package, but there is no signature file next to the package.
+	SignatureMissing
+)
+
+func (p IntegrityProblem) String() string {
+	switch p {
+	case FileMissing:
+		return "missing"
+	case FileTruncated:
+		return "truncated"
+	case FileCorrupted:
+		return "corrupted"
+	case FileSwapped:
+		return "swapped"
+	case SignatureMismatch:
+		return "signature mismatch"
+	case SignatureMissing:
+		return "signature missing"
+	default:
+		return "unknown problem"
+	}
+}
+
+// IntegrityError is returned by VerifyIntegrity when a package file does
+// not match its database entry.
+type IntegrityError struct {
+	Filename string
+	Problem  IntegrityProblem
+	Detail   string
+}
+
+func (e *IntegrityError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s: %s", e.Filename, e.Problem)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Filename, e.Problem, e.Detail)
+}
+
+// VerifyIntegrity checks that the package file that the database entry
+// p refers to has the size and checksum given in the database. The
+// SHA256 checksum is preferred, the MD5 checksum is only used if the
+// entry has no SHA256 checksum. If the entry has a signature, then there
+// must be a signature file next to the package with the same contents.
+//
+// If the file does not match, an *IntegrityError is returned. Other
+// errors are returned if the file cannot be read at all.
+func VerifyIntegrity(p *Package) error {
+	fi, err := os.Stat(p.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IntegrityError{p.Filename, FileMissing, ""}
+		}
+		return err
+	}
+
+	size := uint64(fi.Size())
+	md5sum, sha256sum, err := checksumFile(p.Filename)
+	if err != nil {
+		return err
+	}
+	var mismatch string
+	switch {
+	case p.Size != 0 && size != p.Size:
+		mismatch = fmt.Sprintf("expected %d bytes, got %d", p.Size, size)
+	case p.SHA256Sum != "" && sha256sum != p.SHA256Sum:
+		mismatch = "sha256sum mismatch"
+	case p.SHA256Sum == "" && p.MD5Sum != "" && md5sum != p.MD5Sum:
+		mismatch = "md5sum mismatch"
+	}
+	if mismatch != "" {
+		// If the file is a valid package, then find out if it is another
+		// package than the one that is expected.
+		other, err := Read(p.Filename)
+		if err == nil && (other.Name != p.Name || other.Version != p.Version) {
+			return &IntegrityError{p.Filename, FileSwapped, fmt.Sprintf("contains %s %s", other.Name, other.Version)}
+		}
+		if size < p.Size {
+			return &IntegrityError{p.Filename, FileTruncated, fmt.Sprintf("%d of %d bytes", size, p.Size)}
+		}
+		return &IntegrityError{p.Filename, FileCorrupted, mismatch}
+	}
+
+	if p.PGPSignature == "" {
+		return nil
+	}
+	bs, err := os.ReadFile(p.Filename + ".sig")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IntegrityError{p.Filename + ".sig", SignatureMissing, "database has a signature"}
+		}
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(p.PGPSignature)
+	if err != nil || !bytes.Equal(sig, bs) {
+		return &IntegrityError{p.Filename + ".sig", SignatureMismatch, "differs from database"}
+	}
+	return nil
+}
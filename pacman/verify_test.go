@@ -0,0 +1,109 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	foo := writeTestPackage(t, dir, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo)
+	bar := writeTestPackage(t, dir, "bar-2.1-3-x86_64.pkg.tar.gz", testPkgInfoBar)
+	dbpath := filepath.Join(dir, "test.db.tar.gz")
+
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, p := range []string{foo, bar} {
+		if _, err := db.Add(p); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pkgs, err := ReadDatabase(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	entry := pkgs.ToMap()["foo"]
+	if entry.SHA256Sum == "" || entry.MD5Sum == "" || entry.InstalledSize != 4096 {
+		t.Fatalf("expected checksums and isize in database entry, got %+v", entry)
+	}
+	if err := VerifyIntegrity(entry); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	original, _ := os.ReadFile(foo)
+	barData, _ := os.ReadFile(bar)
+	tests := []struct {
+		name string
+		data []byte
+		want IntegrityProblem
+	}{
+		{"truncated", original[:len(original)/2], FileTruncated},
+		{"corrupted", bytes.Repeat([]byte{'x'}, len(original)), FileCorrupted},
+		{"swapped", barData, FileSwapped},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os.WriteFile(foo, tc.data, 0644)
+			var ierr *IntegrityError
+			if err := VerifyIntegrity(entry); !errors.As(err, &ierr) || ierr.Problem != tc.want {
+				t.Errorf("expected %s, got %v", tc.want, err)
+			}
+		})
+	}
+
+	os.Remove(foo)
+	var ierr *IntegrityError
+	if err := VerifyIntegrity(entry); !errors.As(err, &ierr) || ierr.Problem != FileMissing {
+		t.Errorf("expected %s, got %v", FileMissing, err)
+	}
+}
+
+func TestVerifyIntegritySignature(t *testing.T) {
+	dir := t.TempDir()
+	foo := writeTestPackage(t, dir, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo)
+	if err := os.WriteFile(foo+".sig", []byte("signature"), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	dbpath := filepath.Join(dir, "test.db.tar.gz")
+
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := db.Add(foo); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pkgs, err := ReadDatabase(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	entry := pkgs.ToMap()["foo"]
+	if err := VerifyIntegrity(entry); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	os.WriteFile(foo+".sig", []byte("other"), 0644)
+	var ierr *IntegrityError
+	if err := VerifyIntegrity(entry); !errors.As(err, &ierr) || ierr.Problem != SignatureMismatch {
+		t.Errorf("expected %s, got %v", SignatureMismatch, err)
+	}
+	os.Remove(foo + ".sig")
+	if err := VerifyIntegrity(entry); !errors.As(err, &ierr) || ierr.Problem != SignatureMissing {
+		t.Errorf("expected %s, got %v", SignatureMissing, err)
+	}
+}
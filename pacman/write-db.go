@@ -0,0 +1,561 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goulash/archive"
+	"github.com/goulash/osutil"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ErrDatabaseLocked is returned when a database cannot be written to,
+// because another process holds the lock.
+var ErrDatabaseLocked = errors.New("database is locked")
+
+// DatabaseWriter modifies a repository database in the same way that
+// repo-add and repo-remove do, without requiring either of them.
+// The files database that accompanies the package database, as given
+// by FilesDatabasePath, is kept in sync with it.
+//
+// All changes are kept in memory until Commit is called, at which point
+// the entire database is rewritten in one go. Entries that are already in
+// the database and which are not touched are copied over verbatim.
+//
+// Like repo-add, the database is locked from the moment it is opened until
+// Commit or Close is called, so that no other process can modify it in
+// the meantime.
+type DatabaseWriter struct {
+	path    string
+	entries map[string]*dbEntry
+	unlock  func()
+}
+
+// dbEntry is a single package entry in a database, such as
+// "pacman-6.1.0-3/", together with the files that it contains.
+//
+// The "files" file is only written to the files database.
+type dbEntry struct {
+	dir   string
+	files map[string][]byte
+}
+
+// OpenDatabaseWriter returns a new DatabaseWriter for the database at dbpath.
+// If the database exists, all of its entries are read; if it does not exist,
+// then Commit will create it. File lists are read from the files database;
+// if it is missing, they are rebuilt from the package files next to the
+// database, so that Commit does not write an empty files database.
+//
+// The database is locked until Commit or Close is called. If it is already
+// locked, an error wrapping ErrDatabaseLocked is returned.
+func OpenDatabaseWriter(dbpath string) (*DatabaseWriter, error) {
+	if _, err := compressionFor(dbpath); err != nil {
+		return nil, fmt.Errorf("open database %s: %w", dbpath, err)
+	}
+	unlock, err := lockDatabase(dbpath)
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %w", dbpath, err)
+	}
+
+	w := &DatabaseWriter{
+		path:    dbpath,
+		entries: make(map[string]*dbEntry),
+		unlock:  unlock,
+	}
+	if err := w.read(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// read reads the entries of the database and the file lists of the files
+// database, if they exist.
+func (w *DatabaseWriter) read() error {
+	dbpath := w.path
+	ex, err := osutil.FileExists(dbpath)
+	if err != nil {
+		return fmt.Errorf("open database %s: %w", dbpath, err)
+	}
+	if !ex {
+		return nil
+	}
+
+	debugf("Read database %s\n", dbpath)
+	entries, err := readDatabaseEntries(dbpath)
+	if err != nil {
+		return fmt.Errorf("open database %s: %w", dbpath, err)
+	}
+	index := make(map[string]*dbEntry)
+	filenames := make(map[string]string)
+	for _, e := range entries {
+		pkg, err := readDatabasePkgInfo(bytes.NewReader(e.files["desc"]))
+		if err != nil {
+			return fmt.Errorf("open database %s: entry %s: %w", dbpath, e.dir, err)
+		}
+		w.entries[pkg.Name] = e
+		index[e.dir] = e
+		if pkg.Filename != "" {
+			filenames[e.dir] = filepath.Join(filepath.Dir(dbpath), pkg.Filename)
+		}
+	}
+
+	filespath := FilesDatabasePath(dbpath)
+	if ex, _ := osutil.FileExists(filespath); !ex {
+		w.rebuildFileLists(index, filenames)
+		return nil
+	}
+	debugf("Read database %s\n", filespath)
+	entries, err = readDatabaseEntries(filespath)
+	if err != nil {
+		return fmt.Errorf("open database %s: %w", filespath, err)
+	}
+	for _, fe := range entries {
+		if e, ok := index[fe.dir]; ok {
+			if bs, ok := fe.files["files"]; ok {
+				e.files["files"] = bs
+			}
+		}
+	}
+	return nil
+}
+
+// rebuildFileLists reads the file lists of the entries in index from their
+// package files, for when the files database does not exist. Entries whose
+// package file cannot be read are left without a file list.
+func (w *DatabaseWriter) rebuildFileLists(index map[string]*dbEntry, filenames map[string]string) {
+	debugf("Files database missing, rebuilding file lists for %s\n", w.path)
+	for dir, e := range index {
+		files, err := ReadFileList(filenames[dir])
+		if err != nil {
+			debugf("Warning: cannot rebuild file list for %s: %s\n", strings.TrimSuffix(dir, "/"), err)
+			continue
+		}
+		var buf bytes.Buffer
+		writeDescField(&buf, "FILES", files...)
+		e.files["files"] = buf.Bytes()
+	}
+}
+
+// FilesDatabasePath returns the path of the files database that belongs
+// to the package database at dbpath, for example:
+//
+//	/srv/repo/sirius.db.tar.zst -> /srv/repo/sirius.files.tar.zst
+func FilesDatabasePath(dbpath string) string {
+	dir, base := filepath.Split(dbpath)
+	if i := strings.LastIndex(base, ".db"); i != -1 {
+		return dir + base[:i] + ".files" + base[i+3:]
+	}
+	return dbpath + ".files"
+}
+
+// readDatabaseEntries reads all entries in the database, keeping the
+// contents of each file as is.
+func readDatabaseEntries(dbpath string) ([]*dbEntry, error) {
+	dr, err := archive.NewDecompressor(dbpath)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+
+	var entries []*dbEntry
+	index := make(map[string]*dbEntry)
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		name := strings.Trim(path.Clean(hdr.Name), "/")
+		dir, file := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		if hdr.Typeflag == tar.TypeDir {
+			dir, file = name, ""
+		}
+		if dir == "" || strings.Contains(dir, "/") {
+			return nil, fmt.Errorf("unexpected file '%s'", hdr.Name)
+		}
+
+		e, ok := index[dir]
+		if !ok {
+			e = &dbEntry{dir: dir, files: make(map[string][]byte)}
+			index[dir] = e
+			entries = append(entries, e)
+		}
+		if file == "" {
+			continue
+		}
+		bs, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		e.files[file] = bs
+	}
+
+	for _, e := range entries {
+		if _, ok := e.files["desc"]; !ok {
+			return nil, fmt.Errorf("entry %s has no desc file", e.dir)
+		}
+	}
+	return entries, nil
+}
+
+// Path returns the path to the database that is being written.
+func (w *DatabaseWriter) Path() string { return w.path }
+
+// Names returns a sorted list of all package names in the database.
+func (w *DatabaseWriter) Names() []string {
+	names := make([]string, 0, len(w.entries))
+	for k := range w.entries {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has returns whether there is an entry for pkgname in the database.
+func (w *DatabaseWriter) Has(pkgname string) bool {
+	_, ok := w.entries[pkgname]
+	return ok
+}
+
+// Add reads the package file and adds it to the database, replacing any
+// entry with the same name. If a signature file pkgfile.sig exists, then
+// it is included in the database entry.
+func (w *DatabaseWriter) Add(pkgfile string) (*Package, error) {
+	pkg, err := Read(pkgfile)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newDatabaseEntry(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("add package %s: %w", pkgfile, err)
+	}
+	files, err := ReadFileList(pkgfile)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeDescField(&buf, "FILES", files...)
+	e.files["files"] = buf.Bytes()
+
+	w.entries[pkg.Name] = e
+	return pkg, nil
+}
+
+// Remove removes the entry for pkgname from the database, and returns
+// whether there was such an entry.
+func (w *DatabaseWriter) Remove(pkgname string) bool {
+	if _, ok := w.entries[pkgname]; !ok {
+		return false
+	}
+	delete(w.entries, pkgname)
+	return true
+}
+
+// Commit writes the database and the files database to disk.
+//
+// Both databases are first written to temporary files in the same directory,
+// which are then renamed to the database paths. If the files database cannot
+// be renamed, the previous database is restored, so that the two databases
+// stay consistent. Finally, the short symlinks to the databases (such as
+// "name.db") are created if they do not yet exist. The database lock is
+// released afterwards, even if an error occurs, so the DatabaseWriter
+// cannot be used after Commit.
+func (w *DatabaseWriter) Commit() error {
+	if w.unlock == nil {
+		return fmt.Errorf("write database %s: writer is closed", w.path)
+	}
+	defer w.Close()
+
+	filespath := FilesDatabasePath(w.path)
+	tmppath, filestmppath := w.path+".tmp", filespath+".tmp"
+	defer os.Remove(tmppath)
+	defer os.Remove(filestmppath)
+	debugf("Write database %s\n", w.path)
+	if err := w.writeFile(tmppath, false); err != nil {
+		return fmt.Errorf("write database %s: %w", w.path, err)
+	}
+	debugf("Write database %s\n", filespath)
+	if err := w.writeFile(filestmppath, true); err != nil {
+		return fmt.Errorf("write database %s: %w", filespath, err)
+	}
+
+	// Keep a link to the previous database, so that it can be restored.
+	backup := w.path + ".old"
+	os.Remove(backup)
+	_, err := os.Stat(w.path)
+	existed := err == nil
+	if existed {
+		if err := os.Link(w.path, backup); err != nil {
+			return fmt.Errorf("write database %s: %w", w.path, err)
+		}
+		defer os.Remove(backup)
+	}
+
+	if err := os.Rename(tmppath, w.path); err != nil {
+		return fmt.Errorf("write database %s: %w", w.path, err)
+	}
+	if err := os.Rename(filestmppath, filespath); err != nil {
+		var rerr error
+		if existed {
+			rerr = os.Rename(backup, w.path)
+		} else {
+			rerr = os.Remove(w.path)
+		}
+		if rerr != nil {
+			return fmt.Errorf("write database %s: %w (cannot restore %s, databases are inconsistent: %s)", filespath, err, w.path, rerr)
+		}
+		return fmt.Errorf("write database %s: %w", filespath, err)
+	}
+
+	if err := linkDatabase(w.path); err != nil {
+		return err
+	}
+	return linkDatabase(filespath)
+}
+
+// Close releases the database lock without writing anything.
+// It is safe to call Close after Commit or more than once.
+func (w *DatabaseWriter) Close() error {
+	if w.unlock != nil {
+		w.unlock()
+		w.unlock = nil
+	}
+	return nil
+}
+
+func (w *DatabaseWriter) writeFile(filename string, withFiles bool) (err error) {
+	compress, _ := compressionFor(w.path)
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := f.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	cw, err := compress(f)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(cw)
+
+	entries := make([]*dbEntry, 0, len(w.entries))
+	for _, e := range w.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].dir < entries[j].dir })
+
+	now := time.Now()
+	for _, e := range entries {
+		err = tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     e.dir + "/",
+			Mode:     0755,
+			ModTime:  now,
+		})
+		if err != nil {
+			return err
+		}
+
+		files := make([]string, 0, len(e.files))
+		for k := range e.files {
+			if k == "files" && !withFiles {
+				continue
+			}
+			files = append(files, k)
+		}
+		sort.Strings(files)
+		for _, k := range files {
+			bs := e.files[k]
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     e.dir + "/" + k,
+				Mode:     0644,
+				Size:     int64(len(bs)),
+				ModTime:  now,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err = tw.Write(bs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// newDatabaseEntry creates a database entry for the package, which must
+// have been read from a file.
+func newDatabaseEntry(pkg *Package) (*dbEntry, error) {
+	fi, err := os.Stat(pkg.Filename)
+	if err != nil {
+		return nil, err
+	}
+	md5sum, sha256sum, err := checksumFile(pkg.Filename)
+	if err != nil {
+		return nil, err
+	}
+	var pgpsig string
+	if bs, err := os.ReadFile(pkg.Filename + ".sig"); err == nil {
+		pgpsig = base64.StdEncoding.EncodeToString(bs)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// The sizes, checksums, and signature are those of the file,
+	// not whatever the package says. Unknown fields of the .PKGINFO
+	// have no place in the database.
+	info := *pkg
+	info.Size = uint64(fi.Size())
+	info.MD5Sum, info.SHA256Sum = md5sum, sha256sum
+	info.PGPSignature = pgpsig
+	info.Extras = nil
+
+	e := &dbEntry{
+		dir:   pkg.Name + "-" + pkg.Version,
+		files: map[string][]byte{"desc": MarshalDesc(&info)},
+	}
+	return e, nil
+}
+
+// writeDescField writes a field in the format used by desc files.
+// If there are no values or only an empty value, nothing is written.
+func writeDescField(buf *bytes.Buffer, key string, values ...string) {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		return
+	}
+	fmt.Fprintf(buf, "%%%s%%\n", key)
+	for _, v := range values {
+		buf.WriteString(v)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+}
+
+// checksumFile returns the hex-encoded MD5 and SHA256 sums of the file.
+func checksumFile(filename string) (string, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	m, s := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(m, s), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(m.Sum(nil)), hex.EncodeToString(s.Sum(nil)), nil
+}
+
+// compressionFor returns a function that wraps a writer with the compression
+// that is implied by the extension of the database path. The extensions are
+// those in alpm.DatabaseExtensions.
+func compressionFor(dbpath string) (func(io.Writer) (io.WriteCloser, error), error) {
+	switch filepath.Ext(dbpath) {
+	case ".tar":
+		return func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil }, nil
+	case ".gz":
+		return func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }, nil
+	case ".xz":
+		return func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) }, nil
+	case ".zst":
+		return func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }, nil
+	case ".bz2":
+		return nil, errors.New("writing bzip2 compressed databases is not supported")
+	default:
+		return nil, fmt.Errorf("unknown database extension '%s'", filepath.Ext(dbpath))
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// lockDatabase creates the lock file for the database, and returns
+// a function that removes it again.
+func lockDatabase(dbpath string) (func(), error) {
+	lockpath := dbpath + ".lck"
+	f, err := os.OpenFile(lockpath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDatabaseLocked, lockpath)
+		}
+		return nil, err
+	}
+	f.Close()
+	return func() { os.Remove(lockpath) }, nil
+}
+
+// linkDatabase creates the symlink that pacman clients request, such as
+// "name.db" pointing to "name.db.tar.zst". An existing file that is not
+// a symlink is left alone.
+func linkDatabase(dbpath string) error {
+	i := strings.LastIndex(dbpath, ".tar")
+	if i == -1 {
+		return nil
+	}
+	return symlink(filepath.Base(dbpath), dbpath[:i])
+}
+
+// LinkDatabaseSignature creates the symlink from name.db.sig to the
+// signature of the database at dbpath, such as name.db.tar.zst.sig,
+// which is where pacman looks for the signature of a database.
+func LinkDatabaseSignature(dbpath string) error {
+	i := strings.LastIndex(dbpath, ".tar")
+	if i == -1 {
+		return nil
+	}
+	return symlink(filepath.Base(dbpath)+".sig", dbpath[:i]+".sig")
+}
+
+// symlink makes link point to target, unless link exists and is not
+// a symlink.
+func symlink(target, link string) error {
+	if fi, err := os.Lstat(link); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			debugf("Not replacing non-symlink %s\n", link)
+			return nil
+		}
+		if dst, _ := os.Readlink(link); dst == target {
+			return nil
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, link)
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pacman
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestPackage creates a gzip-compressed package file in dir containing
+// the given .PKGINFO and empty files, and returns its path. Files ending
+// with a slash are created as directories.
+func writeTestPackage(t *testing.T, dir, filename, pkginfo string, files ...string) string {
+	t.Helper()
+
+	p := filepath.Join(dir, filename)
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	err = tw.WriteHeader(&tar.Header{Name: ".PKGINFO", Mode: 0644, Size: int64(len(pkginfo))})
+	if err == nil {
+		_, err = tw.Write([]byte(pkginfo))
+	}
+	for _, f := range files {
+		if err != nil {
+			break
+		}
+		if strings.HasSuffix(f, "/") {
+			err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: f, Mode: 0755})
+		} else {
+			err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f, Mode: 0644})
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return p
+}
+
+const testPkgInfoFoo = `pkgname = foo
+pkgbase = foo
+xdata = pkgtype=pkg
+pkgver = 1.0-1
+pkgdesc = A test package
+url = https://example.com
+builddate = 1700000000
+packager = Nobody <nobody@example.com>
+size = 4096
+arch = any
+license = MIT
+depend = bar>=2
+provides = libfoo.so=1-64
+`
+
+const testPkgInfoBar = `pkgname = bar
+pkgbase = bar
+pkgver = 2.1-3
+pkgdesc = Another test package
+builddate = 1700000000
+size = 1024
+arch = x86_64
+license = GPL
+`
+
+func TestDatabaseWriter(t *testing.T) {
+	for _, ext := range []string{".db.tar", ".db.tar.gz", ".db.tar.xz", ".db.tar.zst"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			foo := writeTestPackage(t, dir, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo)
+			bar := writeTestPackage(t, dir, "bar-2.1-3-x86_64.pkg.tar.gz", testPkgInfoBar)
+			dbpath := filepath.Join(dir, "test"+ext)
+
+			db, err := OpenDatabaseWriter(dbpath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for _, p := range []string{foo, bar} {
+				if _, err := db.Add(p); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if err := db.Commit(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if IsDatabaseLocked(dbpath) {
+				t.Errorf("expected database to be unlocked after commit")
+			}
+			if dst, err := os.Readlink(filepath.Join(dir, "test.db")); err != nil || dst != "test"+ext {
+				t.Errorf("expected symlink test.db -> test%s, got %q (%v)", ext, dst, err)
+			}
+
+			pkgs, err := ReadDatabase(dbpath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			m := pkgs.ToMap()
+			if len(m) != 2 || m["foo"] == nil || m["bar"] == nil {
+				t.Fatalf("expected foo and bar in database, got %v", pkgs)
+			}
+			if m["foo"].Version != "1.0-1" || len(m["foo"].Depends) != 1 || m["foo"].Depends[0] != "bar>=2" {
+				t.Errorf("unexpected entry for foo: %+v", m["foo"])
+			}
+			if m["foo"].Filename != filepath.Join(dir, "foo-1.0-1-any.pkg.tar.gz") {
+				t.Errorf("unexpected filename for foo: %s", m["foo"].Filename)
+			}
+			if !reflect.DeepEqual(m["foo"].Xdata, []string{"pkgtype=pkg"}) {
+				t.Errorf("expected xdata of foo in database, got %q", m["foo"].Xdata)
+			}
+			entries, err := readDatabaseEntries(dbpath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for _, e := range entries {
+				if _, ok := e.files["depends"]; ok {
+					t.Errorf("unexpected depends file in entry %s", e.dir)
+				}
+			}
+
+			// Reopen the database and remove one of the entries.
+			db, err = OpenDatabaseWriter(dbpath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !db.Remove("foo") || db.Remove("baz") {
+				t.Errorf("unexpected result from Remove")
+			}
+			if err := db.Commit(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			pkgs, err = ReadDatabase(dbpath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(pkgs) != 1 || pkgs[0].Name != "bar" || pkgs[0].Version != "2.1-3" {
+				t.Errorf("expected only bar in database, got %v", pkgs)
+			}
+		})
+	}
+}
+
+func TestFilesDatabase(t *testing.T) {
+	dir := t.TempDir()
+	foo := writeTestPackage(t, dir, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo,
+		"usr/", "usr/bin/", "usr/bin/foo", "usr/share/", "usr/share/foo/", "usr/share/foo/README")
+	bar := writeTestPackage(t, dir, "bar-2.1-3-x86_64.pkg.tar.gz", testPkgInfoBar,
+		"usr/", "usr/lib/", "usr/lib/libbar.so")
+	dbpath := filepath.Join(dir, "test.db.tar.zst")
+
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, p := range []string{foo, bar} {
+		if _, err := db.Add(p); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	filespath := FilesDatabasePath(dbpath)
+	if filespath != filepath.Join(dir, "test.files.tar.zst") {
+		t.Fatalf("unexpected files database path: %s", filespath)
+	}
+	if _, err := os.Readlink(filepath.Join(dir, "test.files")); err != nil {
+		t.Errorf("expected symlink test.files: %s", err)
+	}
+
+	pkgs, err := ReadFilesDatabase(filespath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("expected 2 packages in files database, got %d", len(pkgs))
+	}
+	m := make(map[string]*PackageFiles)
+	for _, p := range pkgs {
+		m[p.Name] = p
+	}
+	want := []string{"usr/", "usr/bin/", "usr/bin/foo", "usr/share/", "usr/share/foo/", "usr/share/foo/README"}
+	if !reflect.DeepEqual(m["foo"].Files, want) {
+		t.Errorf("expected files %q, got %q", want, m["foo"].Files)
+	}
+	if !m["foo"].Owns("/usr/bin/foo") || !m["foo"].Owns("README") || m["foo"].Owns("usr/lib/libbar.so") {
+		t.Errorf("unexpected result from Owns for foo")
+	}
+	if !m["bar"].Owns("libbar.so") || m["bar"].Owns("lib") {
+		t.Errorf("unexpected result from Owns for bar")
+	}
+
+	// Removing a package and rewriting must keep the other file lists.
+	db, err = OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db.Remove("bar")
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pkgs, err = ReadFilesDatabase(filespath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 1 || !reflect.DeepEqual(pkgs[0].Files, want) {
+		t.Errorf("expected only foo with its files, got %v", pkgs)
+	}
+
+	// If the files database goes missing, the file lists are rebuilt
+	// from the package files when the database is written again.
+	if err := os.Remove(filespath); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db, err = OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := db.Add(bar); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pkgs, err = ReadFilesDatabase(filespath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m = make(map[string]*PackageFiles)
+	for _, p := range pkgs {
+		m[p.Name] = p
+	}
+	if len(m) != 2 || m["foo"] == nil || !reflect.DeepEqual(m["foo"].Files, want) {
+		t.Errorf("expected file list of foo to be rebuilt, got %v", pkgs)
+	}
+}
+
+func TestDatabaseWriterLocked(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "test.db.tar.gz")
+	if err := os.WriteFile(dbpath+".lck", nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := OpenDatabaseWriter(dbpath); !errors.Is(err, ErrDatabaseLocked) {
+		t.Errorf("expected ErrDatabaseLocked opening locked database, got %v", err)
+	}
+	if err := os.Remove(dbpath + ".lck"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !IsDatabaseLocked(dbpath) {
+		t.Errorf("expected database to be locked while open")
+	}
+	if _, err := OpenDatabaseWriter(dbpath); !errors.Is(err, ErrDatabaseLocked) {
+		t.Errorf("expected ErrDatabaseLocked opening database twice, got %v", err)
+	}
+	db.Close()
+	if IsDatabaseLocked(dbpath) {
+		t.Errorf("expected database to be unlocked after close")
+	}
+}
+
+func TestDatabaseWriterRestore(t *testing.T) {
+	dir := t.TempDir()
+	foo := writeTestPackage(t, dir, "foo-1.0-1-any.pkg.tar.gz", testPkgInfoFoo)
+	bar := writeTestPackage(t, dir, "bar-2.1-3-x86_64.pkg.tar.gz", testPkgInfoBar)
+	dbpath := filepath.Join(dir, "test.db.tar.gz")
+
+	db, err := OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := db.Add(foo); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// If the files database cannot be replaced, the database is restored.
+	db, err = OpenDatabaseWriter(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := db.Add(bar); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	filespath := FilesDatabasePath(dbpath)
+	if err := os.Remove(filespath); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Join(filespath, "blocked"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := db.Commit(); err == nil {
+		t.Fatalf("expected error replacing files database")
+	}
+	pkgs, err := ReadDatabase(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "foo" {
+		t.Errorf("expected previous database with foo, got %v", pkgs)
+	}
+	if matches, _ := filepath.Glob(dbpath + ".*"); len(matches) != 0 {
+		t.Errorf("unexpected files left behind: %v", matches)
+	}
+}
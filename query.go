@@ -5,6 +5,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -57,8 +58,8 @@ var queryCmd = &cobra.Command{
 
 		pkgs, err := aur.ReadAll(args)
 		if err != nil {
-			nfe, ok := err.(*aur.NotFoundError)
-			if !ok {
+			var nfe *aur.NotFoundError
+			if !errors.As(err, &nfe) {
 				return err
 			}
 			for _, n := range nfe.Names {
@@ -169,7 +170,7 @@ func formatAURPackageInfo(p *aur.Package, hspace int) string {
 		fmt.Fprintf(&buf, "    Keywords: %s\n", wrap(p.Keywords, 14))
 	}
 
-	fmt.Fprintf(&buf, "    Snapshot URL: %s%s\n", "https://aur.archlinux.org", p.URLPath)
+	fmt.Fprintf(&buf, "    Snapshot URL: %s\n", p.DownloadURL())
 	fmt.Fprintf(&buf, "    Maintainer: %s\n", p.Maintainer)
 	fmt.Fprintf(&buf, "    Votes: %d\n", p.NumVotes)
 	fmt.Fprintf(&buf, "    Popularity: %f\n", p.Popularity)
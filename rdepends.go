@@ -0,0 +1,65 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rdependsRecursive bool
+	rdependsRaw       bool
+)
+
+func init() {
+	MainCmd.AddCommand(rdependsCmd)
+
+	rdependsCmd.Flags().BoolVarP(&rdependsRecursive, "recursive", "r", false, "include packages that depend on them indirectly")
+	rdependsCmd.Flags().BoolVar(&rdependsRaw, "raw", false, "show only the names")
+}
+
+var rdependsCmd = &cobra.Command{
+	Use:   "rdepends PKGNAME ...",
+	Short: "List packages that depend on the given packages",
+	Long: `List packages in the repository that depend on the given packages.
+
+  A package depends on another if one of its dependencies is satisfied
+  by it, either by name or by what it provides. Only the packages in the
+  repository database are considered. Each dependent package is printed
+  together with the dependency, once for each package that satisfies it.
+  Dependencies that a package other than the given ones also satisfies,
+  such as foo-bin providing foo, are not listed, since removing the given
+  packages would not break them.
+
+  These are the packages that repoctl remove refuses to break, and that
+  repoctl remove --cascade removes as well, which is the same as with
+  the --recursive flag here.
+`,
+	Example: `  repoctl rdepends libfoo
+  repoctl rdepends -r --raw libfoo`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exceptQuiet()
+
+		dependents, err := Repo.ReverseDependencies(rdependsRecursive, args...)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, d := range dependents {
+			if !rdependsRaw {
+				term.Printf("%s requires %s (%s)\n", d.Package.Name, d.Depend, d.On)
+			} else if !seen[d.Package.Name] {
+				seen[d.Package.Name] = true
+				term.Printf("%s\n", d.Package.Name)
+			}
+		}
+		return nil
+	},
+}
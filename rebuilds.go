@@ -0,0 +1,92 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rebuildsRaw  bool
+	rebuildsDown bool
+)
+
+func init() {
+	MainCmd.AddCommand(rebuildsCmd)
+
+	rebuildsCmd.Flags().BoolVar(&rebuildsRaw, "raw", false, "show only the names")
+	rebuildsCmd.Flags().BoolVar(&rebuildsDown, "down", false, "download the packages from AUR, as with the down command")
+}
+
+var rebuildsCmd = &cobra.Command{
+	Use:   "rebuilds [PKGNAME ...]",
+	Short: "List packages that need to be rebuilt for new libraries",
+	Long: `List packages that need to be rebuilt because of new library versions.
+
+  When makepkg builds a package, it records the shared libraries that the
+  package is linked against as dependencies with a version, such as
+  libfoo.so=3-64, and the packages containing the libraries provide them.
+  When a library changes in an incompatible way, the version changes, and
+  packages that depend on the old version need to be rebuilt.
+
+  This command lists every package in the repository that depends on a
+  version of a library that neither the repository nor the sync
+  repositories in the pacman configuration provide, together with the
+  versions that are provided instead. If no packages are given, all
+  packages in the database are checked.
+
+  The sync databases are used as they are, so run pacman -Sy first if
+  they might be out of date.
+
+  With --down, the packages are downloaded from AUR as with the down
+  command, using its default options. Use --raw to pass the names to the
+  down command with other options instead.
+`,
+	Example: `  repoctl rebuilds
+  repoctl rebuilds --down
+  repoctl down -r $(repoctl rebuilds --raw)`,
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rebuildsRaw {
+			exceptQuiet()
+		}
+
+		rebuilds, err := Repo.FindRebuilds(args...)
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		seen := make(map[string]bool)
+		for _, r := range rebuilds {
+			if !seen[r.Package.Name] {
+				seen[r.Package.Name] = true
+				names = append(names, r.Package.Name)
+			}
+			if rebuildsRaw {
+				continue
+			}
+			available := "nothing"
+			if len(r.Available) != 0 {
+				available = strings.Join(r.Available, ", ")
+			}
+			term.Printf("%s: requires %s, but there is %s\n", r.Package.Name, r.Depend, available)
+		}
+		if rebuildsRaw {
+			for _, name := range names {
+				term.Printf("%s\n", name)
+			}
+		}
+		if !rebuildsDown || len(names) == 0 {
+			return nil
+		}
+		return downloadPackages(names)
+	},
+}
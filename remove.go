@@ -5,12 +5,24 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/repo"
 	"github.com/spf13/cobra"
 )
 
+var (
+	removeCascade bool
+	removeForce   bool
+)
+
 func init() {
 	MainCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().BoolVar(&removeCascade, "cascade", false, "remove packages that depend on the packages too")
+	removeCmd.Flags().BoolVar(&removeForce, "force", false, "remove packages even if others depend on them")
 }
 
 var removeCmd = &cobra.Command{
@@ -28,15 +40,29 @@ var removeCmd = &cobra.Command{
   then package files are ignored; repoctl update will add them again.
   In this case, you probably want to use a profile with backup=false to force
   them to be deleted.
+
+  Packages that other packages in the repository depend on, either by
+  name or by what they provide, are not removed, and the packages that
+  depend on them are listed instead. Use --cascade to remove these
+  packages as well, and those that depend on them in turn, or --force
+  to remove the packages nonetheless. Only one of them can be given.
 `,
-	Example:           `  repoctl rm fairsplit`,
+	Example: `  repoctl rm fairsplit
+  repoctl rm --cascade libfoo`,
 	ValidArgsFunction: completeRepoPackageNames,
 	PreRunE:           ProfileInit,
 	PostRunE:          ProfileTeardown,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if removeCascade && removeForce {
+			return fmt.Errorf("--cascade and --force cannot be used together")
+		}
 		if Repo.Backup && Repo.IsObsoleteCached() {
 			term.Warnf("Warning: removing only database entries\n")
 		}
-		return Repo.Remove(nil, args...)
+		err := Repo.RemoveChecked(nil, removeCascade, removeForce, args...)
+		if errors.Is(err, repo.ErrRequired) {
+			return fmt.Errorf("%w; use --cascade or --force", err)
+		}
+		return err
 	},
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman"
@@ -55,17 +56,53 @@ func (r *Repo) add(h errs.Handler, pkgfiles []string, ar func(string, string) er
 		return nil
 	}
 
+	kr, err := r.ReadKeyring()
+	if err != nil {
+		return err
+	}
+	signer, err := r.Signer()
+	if err != nil {
+		return err
+	}
+	db, err := r.ReadDatabase()
+	if err != nil {
+		return err
+	}
+
+	// Each package is checked against the database as it will be after
+	// the packages before it in pkgfiles have been added, so that the
+	// packages in one batch are also checked against each other.
+	batch := newReplaceBatch(db)
+
 	added := make([]string, 0, len(pkgfiles))
+	var replaced pacman.Packages
+	var dropped []string
 	for _, f := range pkgfiles {
 		pkg, err := NewSignedPkg(f)
 		if err != nil {
 			// This means that we are trying to add something that's
 			// non-existant or corrupt.
-			term.Errorf("Skipping %s: %s", f, err)
+			term.Errorf("Skipping %s: %s\n", f, err)
+			continue
+		}
+		info, err := pacman.Read(f)
+		if err != nil {
+			term.Errorf("Skipping %s: %s\n", f, err)
 			continue
 		}
-		if r.RequireSignature && !pkg.HasSignature() {
-			term.Errorf("Skipping %s: require signature but none available", f)
+		// Packages without signature are signed by us once they are in
+		// the repository, but only if they may be added without one.
+		if err := r.VerifySignature(kr, pkg); err != nil {
+			term.Errorf("Skipping %s: %s\n", f, err)
+			continue
+		}
+		sign := signer != nil && !pkg.HasSignature()
+		if by := batch.replacedBy(info.Name); by != "" {
+			term.Errorf("Skipping %s: replaced by %s\n", f, by)
+			continue
+		}
+		ok, remove := batch.check(r, info)
+		if !ok {
 			continue
 		}
 
@@ -81,10 +118,29 @@ func (r *Repo) add(h errs.Handler, pkgfiles []string, ar func(string, string) er
 			}
 			continue
 		}
-		added = append(added, path.Join(r.Directory, path.Base(f)))
+		dst := path.Join(r.Directory, path.Base(f))
+		if sign {
+			if err := signFile(signer, dst); err != nil {
+				err = h(err)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		added = append(added, dst)
+
+		// Packages from this batch that are replaced are simply not added.
+		info.Filename = dst
+		fromDB, fromBatch := batch.accept(info, remove)
+		replaced = append(replaced, fromDB...)
+		for _, x := range fromBatch {
+			added = removeString(added, x.Filename)
+			dropped = append(dropped, x.Filename)
+		}
 	}
 
-	err := r.AddToDatabase(added...)
+	err = r.ModifyDatabase(added, pu.Map(replaced, pu.PkgName))
 	if err != nil {
 		return err
 	}
@@ -93,11 +149,15 @@ func (r *Repo) add(h errs.Handler, pkgfiles []string, ar func(string, string) er
 	if err != nil {
 		return err
 	}
-	return r.Dispatch(h, pu.Map(pkgs, pu.PkgFilename)...)
+	obsolete := append(pu.Map(pkgs, pu.PkgFilename), pu.Map(replaced, pu.PkgFilename)...)
+	obsolete = append(obsolete, dropped...)
+	sort.Strings(obsolete)
+	return r.Dispatch(h, unique(obsolete)...)
 }
 
 // Remove removes the given names from the database and dispatches
-// the files.
+// the files. Packages that depend on them are not checked; use
+// RemoveChecked for that.
 func (r *Repo) Remove(h errs.Handler, pkgnames ...string) error {
 	errs.Init(&h)
 	if len(pkgnames) == 0 {
@@ -241,7 +301,22 @@ func (r *Repo) Update(h errs.Handler, pkgnames ...string) error {
 	if err != nil {
 		return err
 	}
+	kr, err := r.ReadKeyring()
+	if err != nil {
+		return err
+	}
+	signer, err := r.Signer()
+	if err != nil {
+		return err
+	}
+
+	db, err := r.ReadDatabase()
+	if err != nil {
+		return err
+	}
 
+	// As in add, the updates are checked against each other as well.
+	batch := newReplaceBatch(db)
 	var updates []string
 	var obsolete []string
 	var missing []string
@@ -255,29 +330,70 @@ func (r *Repo) Update(h errs.Handler, pkgnames ...string) error {
 		}
 		if p.HasUpdate() || len(pkgnames) > 0 {
 			f := p.Pkg().Filename
-			if r.RequireSignature {
-				spkg, err := NewSignedPkg(f)
-				if err != nil {
-					term.Errorf("Skipping %s: %s", f, err)
-					continue
-				} else if !spkg.HasSignature() {
-					term.Errorf("Skipping %s: require signature but none found", f)
+			spkg, err := NewSignedPkg(f)
+			if err != nil {
+				term.Errorf("Skipping %s: %s\n", f, err)
+				continue
+			}
+			if err := r.VerifySignature(kr, spkg); err != nil {
+				term.Errorf("Skipping %s: %s\n", f, err)
+				continue
+			}
+			if signer != nil && !spkg.HasSignature() {
+				if err := signFile(signer, f); err != nil {
+					term.Errorf("Skipping %s: %s\n", f, err)
 					continue
 				}
 			}
+			// A package that was replaced by another update is removed
+			// rather than updated.
+			if by := batch.replacedBy(p.Name); by != "" {
+				term.Errorf("Skipping %s: replaced by %s\n", f, by)
+				obsolete = append(obsolete, f)
+				continue
+			}
+			ok, remove := batch.check(r, p.Pkg())
+			if !ok {
+				continue
+			}
 			updates = append(updates, f)
+			fromDB, fromBatch := batch.accept(p.Pkg(), remove)
+			missing = append(missing, pu.Map(fromDB, pu.PkgName)...)
+			obsolete = append(obsolete, pu.Map(fromDB, pu.PkgFilename)...)
+			for _, x := range fromBatch {
+				updates = removeString(updates, x.Filename)
+				obsolete = append(obsolete, x.Filename)
+			}
 		}
 	}
 
-	err = r.RemoveFromDatabase(missing...)
+	err = r.ModifyDatabase(updates, missing)
 	if err != nil {
 		return err
 	}
 
-	err = r.AddToDatabase(updates...)
-	if err != nil {
-		return err
+	// Files may be obsolete for several reasons, but are dispatched once.
+	sort.Strings(obsolete)
+	return r.Dispatch(h, unique(obsolete)...)
+}
+
+// removeString returns xs without any occurrences of s.
+func removeString(xs []string, s string) []string {
+	result := xs[:0]
+	for _, x := range xs {
+		if x != s {
+			result = append(result, x)
+		}
 	}
+	return result
+}
 
-	return r.Dispatch(h, obsolete...)
+// containsPkg returns whether p is one of pkgs.
+func containsPkg(pkgs pacman.Packages, p *pacman.Package) bool {
+	for _, q := range pkgs {
+		if q == p {
+			return true
+		}
+	}
+	return false
 }
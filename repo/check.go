@@ -0,0 +1,238 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	pu "github.com/cassava/repoctl/pacman/pkgutil"
+	"github.com/goulash/errs"
+)
+
+// DependencyProblem is the kind of problem that Check finds.
+type DependencyProblem int
+
+const (
+	// Unsatisfied means that no package satisfies a dependency,
+	// either by name or by what it provides, in the required version.
+	Unsatisfied DependencyProblem = iota
+	// Conflicting means that a package conflicts with a package
+	// from one of the sync repositories, or the other way round.
+	Conflicting
+)
+
+func (p DependencyProblem) String() string {
+	switch p {
+	case Unsatisfied:
+		return "unsatisfied"
+	case Conflicting:
+		return "conflict"
+	default:
+		return "unknown problem"
+	}
+}
+
+// DependencyError is passed by Check to the error handler for each
+// problem that it finds.
+type DependencyError struct {
+	// Package is the name of the package in the repository.
+	Package string
+	Problem DependencyProblem
+	// Depend is the dependency that is unsatisfied, or the conflict.
+	Depend alpm.Depend
+	// Other is the conflicting package, such as "extra/foo 1.0-1".
+	Other string
+}
+
+func (e *DependencyError) Error() string {
+	switch e.Problem {
+	case Unsatisfied:
+		return fmt.Sprintf("%s: nothing satisfies dependency %s", e.Package, e.Depend)
+	case Conflicting:
+		return fmt.Sprintf("%s: conflicts with %s (%s)", e.Package, e.Other, e.Depend)
+	default:
+		return fmt.Sprintf("%s: %s", e.Package, e.Problem)
+	}
+}
+
+// Check checks that the packages in the repository can be installed, as
+// far as the repository and the sync repositories of the pacman system
+// are concerned. If pkgnames is empty, all packages in the database are
+// checked.
+//
+// For every dependency of a package that no package in the repository or
+// the sync repositories satisfies, a *DependencyError is passed to h.
+// If conflicts is true, the same happens for every conflict between a
+// package and a package in the sync repositories. Names in pkgnames that
+// are not in the database result in an error wrapping ErrNotInDatabase.
+func (r *Repo) Check(h errs.Handler, conflicts bool, pkgnames ...string) error {
+	errs.Init(&h)
+
+	pkgs, err := r.ReadDatabase()
+	if err != nil {
+		return err
+	}
+
+	sync, err := r.readSyncIndex()
+	if err != nil {
+		return err
+	}
+	own := newSyncIndex()
+	own.add(r.Name(), pkgs)
+
+	if len(pkgnames) != 0 {
+		pkgs = pu.Filter(pkgs, pu.NameFltr(pkgnames)).(pacman.Packages)
+		if err := missingNames(h, pkgs, pkgnames); err != nil {
+			return err
+		}
+	}
+	for _, p := range pkgs {
+		term.Debugf("Checking: %s\n", p.Name)
+		for _, d := range alpm.ParseDepends(p.Depends) {
+			if own.satisfies(d) || sync.satisfies(d) {
+				continue
+			}
+			err := &DependencyError{Package: p.Name, Problem: Unsatisfied, Depend: d}
+			if err := h(err); err != nil {
+				return err
+			}
+		}
+		if !conflicts {
+			continue
+		}
+		for _, e := range sync.conflicts(p) {
+			if err := h(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readSyncIndex reads the sync databases of the pacman system into an index.
+// The repository itself is skipped, since it may be enabled in pacman.conf,
+// but we are interested in the database, not the synced copy.
+func (r *Repo) readSyncIndex() (*syncIndex, error) {
+	sys := r.System
+	if sys == nil {
+		sys = pacman.DefaultSystem()
+	}
+	c, err := sys.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	x := newSyncIndex()
+	for _, name := range c.RepositoryNames() {
+		if name == r.Name() {
+			continue
+		}
+		pkgs, err := pacman.ReadDatabase(c.SyncDatabasePath(name))
+		if err != nil {
+			return nil, err
+		}
+		x.add(name, pkgs)
+	}
+	return x, nil
+}
+
+// syncEntry is a package together with the name of its repository.
+type syncEntry struct {
+	repo string
+	pkg  *pacman.Package
+}
+
+func (e syncEntry) String() string {
+	return fmt.Sprintf("%s/%s %s", e.repo, e.pkg.Name, e.pkg.Version)
+}
+
+// syncIndex makes it possible to find packages by name, by what they
+// provide, and by what they conflict with.
+type syncIndex struct {
+	byName      map[string][]syncEntry
+	byProvides  map[string][]syncEntry
+	byConflicts map[string][]syncEntry
+}
+
+func newSyncIndex() *syncIndex {
+	return &syncIndex{
+		byName:      make(map[string][]syncEntry),
+		byProvides:  make(map[string][]syncEntry),
+		byConflicts: make(map[string][]syncEntry),
+	}
+}
+
+func (x *syncIndex) add(repo string, pkgs pacman.Packages) {
+	for _, p := range pkgs {
+		e := syncEntry{repo, p}
+		x.byName[p.Name] = append(x.byName[p.Name], e)
+		for _, name := range alpm.DependNames(p.Provides) {
+			x.byProvides[name] = append(x.byProvides[name], e)
+		}
+		for _, name := range alpm.DependNames(p.Conflicts) {
+			x.byConflicts[name] = append(x.byConflicts[name], e)
+		}
+	}
+}
+
+// satisfies returns whether any package in the index satisfies d.
+func (x *syncIndex) satisfies(d alpm.Depend) bool {
+	return len(x.candidates(d)) != 0
+}
+
+// candidates returns the packages in the index that satisfy d.
+func (x *syncIndex) candidates(d alpm.Depend) []syncEntry {
+	var es []syncEntry
+	for _, e := range x.byName[d.Name] {
+		if e.pkg.Satisfies(d) {
+			es = append(es, e)
+		}
+	}
+	for _, e := range x.byProvides[d.Name] {
+		if e.pkg.Name != d.Name && e.pkg.Satisfies(d) {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+// conflicts returns an error for every package in the index that p
+// conflicts with, or that conflicts with p. Packages with the same name
+// as p are not considered, since they replace p instead.
+func (x *syncIndex) conflicts(p *pacman.Package) []error {
+	var result []error
+	seen := make(map[*pacman.Package]bool)
+	report := func(e syncEntry, d alpm.Depend) {
+		if e.pkg.Name == p.Name || seen[e.pkg] {
+			return
+		}
+		seen[e.pkg] = true
+		result = append(result, &DependencyError{
+			Package: p.Name,
+			Problem: Conflicting,
+			Depend:  d,
+			Other:   e.String(),
+		})
+	}
+
+	for _, d := range alpm.ParseDepends(p.Conflicts) {
+		for _, e := range x.candidates(d) {
+			report(e, d)
+		}
+	}
+	names := append([]string{p.Name}, alpm.DependNames(p.Provides)...)
+	for _, name := range names {
+		for _, e := range x.byConflicts[name] {
+			for _, d := range alpm.ParseDepends(e.pkg.Conflicts) {
+				if d.Name == name && p.Satisfies(d) {
+					report(e, d)
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+)
+
+// testPkg returns a package with the given name and version, which
+// may be followed by fields such as "depends=foo>=2" or "provides=foo".
+func testPkg(name, version string, fields ...string) *pacman.Package {
+	p := &pacman.Package{Name: name, Version: version}
+	for _, f := range fields {
+		k, v, _ := strings.Cut(f, "=")
+		switch k {
+		case "depends":
+			p.Depends = append(p.Depends, v)
+		case "provides":
+			p.Provides = append(p.Provides, v)
+		case "conflicts":
+			p.Conflicts = append(p.Conflicts, v)
+		case "replaces":
+			p.Replaces = append(p.Replaces, v)
+		default:
+			panic("unknown field " + k)
+		}
+	}
+	return p
+}
+
+// writeTestDatabase writes a database at dbpath that contains pkgs.
+func writeTestDatabase(t *testing.T, dbpath string, pkgs ...*pacman.Package) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(dbpath), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f, err := os.Create(dbpath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, p := range pkgs {
+		if p.Filename == "" {
+			p.Filename = p.Name + "-" + p.Version + "-any.pkg.tar.zst"
+		}
+		desc := pacman.MarshalDesc(p)
+		dir := p.Name + "-" + p.Version + "/"
+		tw.WriteHeader(&tar.Header{Name: dir, Typeflag: tar.TypeDir, Mode: 0755})
+		tw.WriteHeader(&tar.Header{Name: dir + "desc", Mode: 0644, Size: int64(len(desc))})
+		tw.Write(desc)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// newTestRepo returns a repository named "test" whose database contains
+// pkgs, with a pacman system that has the given sync repositories.
+func newTestRepo(t *testing.T, pkgs pacman.Packages, sync map[string]pacman.Packages) *Repo {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+
+	names := make([]string, 0, len(sync))
+	for name := range sync {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	conf := "[options]\n"
+	for _, name := range names {
+		conf += "[" + name + "]\n"
+		writeTestDatabase(t, filepath.Join(root, "var", "lib", "pacman", "sync", name+".db"), sync[name]...)
+	}
+	os.MkdirAll(filepath.Join(root, "etc"), 0755)
+	if err := os.WriteFile(filepath.Join(root, "etc", "pacman.conf"), []byte(conf), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r := New(filepath.Join(dir, "repo", "test.db.tar.gz"))
+	r.System = &pacman.System{Root: root}
+	writeTestDatabase(t, r.DatabasePath(), pkgs...)
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	pkgs := pacman.Packages{
+		testPkg("app", "1.0-1", "depends=lib>=2", "depends=glibc", "depends=java-runtime>=17"),
+		testPkg("lib", "2.1-1"),
+		testPkg("old", "1.0-1", "depends=lib<2", "depends=gone"),
+		testPkg("foo-git", "r10-1", "provides=foo=2.0", "conflicts=foo"),
+		testPkg("ours", "1.0-1", "provides=bar"),
+	}
+	sync := map[string]pacman.Packages{
+		"core":  {testPkg("glibc", "2.38-7")},
+		"extra": {testPkg("jre-openjdk", "21-1", "provides=java-runtime=21"), testPkg("foo", "1.5-1"), testPkg("bar", "1.0-1", "conflicts=bar")},
+		// The repository itself is ignored when it is enabled in pacman.conf.
+		"test": {testPkg("gone", "1.0-1")},
+	}
+	r := newTestRepo(t, pkgs, sync)
+
+	var got []string
+	err := r.Check(func(err error) error {
+		got = append(got, err.Error())
+		return nil
+	}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{
+		"old: nothing satisfies dependency lib<2",
+		"old: nothing satisfies dependency gone",
+		"foo-git: conflicts with extra/foo 1.5-1 (foo)",
+		"ours: conflicts with extra/bar 1.0-1 (bar)",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("expected problems %q, got %q", want, got)
+	}
+
+	// Without conflicts and only for some packages, unknown names
+	// are reported as well.
+	got = nil
+	var missing []string
+	err = r.Check(func(err error) error {
+		if errors.Is(err, ErrNotInDatabase) {
+			missing = append(missing, err.Error())
+			return nil
+		}
+		got = append(got, err.Error())
+		return nil
+	}, false, "app", "foo-git", "typo")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no problems, got %q", got)
+	}
+	if !equalStrings(missing, []string{"package not in database: typo"}) {
+		t.Errorf("expected typo to be reported, got %q", missing)
+	}
+}
+
+func TestSyncIndex(t *testing.T) {
+	x := newSyncIndex()
+	x.add("extra", pacman.Packages{
+		testPkg("foo", "1.5-1", "provides=libfoo.so=1-64"),
+		testPkg("foo-git", "r10-1", "provides=foo=2.0", "conflicts=foo"),
+		testPkg("jre-openjdk", "21-1", "provides=java-runtime=21", "provides=java-runtime-headless"),
+	})
+
+	tests := []struct {
+		depend string
+		want   []string
+	}{
+		{"foo", []string{"extra/foo 1.5-1", "extra/foo-git r10-1"}},
+		{"foo>=2", []string{"extra/foo-git r10-1"}},
+		{"foo<1", nil},
+		{"libfoo.so=1-64", []string{"extra/foo 1.5-1"}},
+		{"libfoo.so=2-64", nil},
+		{"java-runtime>=17", []string{"extra/jre-openjdk 21-1"}},
+		{"java-runtime-headless>=17", nil},
+		{"java-runtime-headless", []string{"extra/jre-openjdk 21-1"}},
+		{"bar", nil},
+	}
+	for _, tc := range tests {
+		d := alpm.ParseDepend(tc.depend)
+		var got []string
+		for _, e := range x.candidates(d) {
+			got = append(got, e.String())
+		}
+		if !equalStrings(got, tc.want) {
+			t.Errorf("%s: expected candidates %q, got %q", tc.depend, tc.want, got)
+		}
+		if x.satisfies(d) != (len(tc.want) != 0) {
+			t.Errorf("%s: unexpected result from satisfies", tc.depend)
+		}
+	}
+
+	// Conflicts are found in both directions, but not with packages
+	// of the same name.
+	var got []string
+	for _, err := range x.conflicts(testPkg("foo", "1.6-1", "conflicts=foo-git")) {
+		got = append(got, err.Error())
+	}
+	want := []string{"foo: conflicts with extra/foo-git r10-1 (foo-git)"}
+	if !equalStrings(got, want) {
+		t.Errorf("expected conflicts %q, got %q", want, got)
+	}
+	got = nil
+	for _, err := range x.conflicts(testPkg("foo-bin", "2.0-1", "provides=foo=2.0")) {
+		got = append(got, err.Error())
+	}
+	want = []string{"foo-bin: conflicts with extra/foo-git r10-1 (foo)"}
+	if !equalStrings(got, want) {
+		t.Errorf("expected conflicts %q, got %q", want, got)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -5,27 +5,25 @@
 package repo
 
 import (
-	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman"
 	"github.com/goulash/osutil"
 )
 
-var (
-	SystemRepoAdd    = "repo-add"
-	SystemRepoRemove = "repo-remove"
-)
-
-// DeleteDatabase deletes the repository database (but not the files).
+// DeleteDatabase deletes the repository database and the files database
+// with their signatures (but not the package files).
 func (r *Repo) DeleteDatabase() error {
-	dbpath := r.DatabasePath()
-	if ex, _ := osutil.FileExists(dbpath); ex {
-		term.Printf("Deleting database: %s\n", dbpath)
-		return os.Remove(dbpath)
+	dbpath, filespath := r.DatabasePath(), r.FilesDatabasePath()
+	for _, dbpath := range []string{dbpath, dbpath + ".sig", filespath, filespath + ".sig"} {
+		if ex, _ := osutil.FileExists(dbpath); ex {
+			term.Printf("Deleting database: %s\n", dbpath)
+			err := os.Remove(dbpath)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -41,104 +39,56 @@ func (r *Repo) CreateDatabase() error {
 	}
 
 	term.Printf("Creating database: %s\n", dbpath)
-	args := joinArgs(r.AddParameters, dbpath)
-	cmd := exec.Command(SystemRepoAdd, args...)
-	return r.system(cmd)
+	return r.ModifyDatabase(nil, nil)
 }
 
 // AddToDatabase adds the given packages to the repository database.
 func (r *Repo) AddToDatabase(pkgfiles ...string) error {
-	if len(pkgfiles) == 0 {
-		return nil
-	}
-
-	dbpath := r.DatabasePath()
-	if pacman.IsDatabaseLocked(dbpath) {
-		return fmt.Errorf("database is locked: %s.lck", dbpath)
-	}
-
-	return in(r.Directory, func() error {
-		for _, p := range pkgfiles {
-			term.Printf("Adding package to database: %s\n", p)
-		}
-
-		args := joinArgs(r.AddParameters, r.Database, pkgfiles)
-		cmd := exec.Command(SystemRepoAdd, args...)
-		return r.system(cmd)
-	})
+	return r.ModifyDatabase(pkgfiles, nil)
 }
 
 // RemoveFromDatabase removes the given packages from the repository database.
 func (r *Repo) RemoveFromDatabase(pkgnames ...string) error {
-	if len(pkgnames) == 0 {
-		return nil
-	}
+	return r.ModifyDatabase(nil, pkgnames)
+}
 
+// ModifyDatabase removes the package names in remove from the database and
+// adds the package files in add to the database. The database and the
+// files database are only written once, after all changes have been made.
+//
+// If the database does not exist, it is created. If the repository has a
+// signing key, the databases are signed afterwards.
+func (r *Repo) ModifyDatabase(add []string, remove []string) error {
 	dbpath := r.DatabasePath()
-	if pacman.IsDatabaseLocked(dbpath) {
-		return fmt.Errorf("database is locked: %s.lck", dbpath)
+	ex, _ := osutil.FileExists(dbpath)
+	if ex && len(add) == 0 && len(remove) == 0 {
+		return nil
 	}
 
-	return in(r.Directory, func() error {
-		for _, p := range pkgnames {
-			term.Printf("Removing package from database: %s\n", p)
-		}
-
-		args := joinArgs(r.RemoveParameters, r.Database, pkgnames)
-		cmd := exec.Command(SystemRepoRemove, args...)
-		return r.system(cmd)
-	})
-}
-
-// joinArgs joins strings and arrays of strings together into one array.
-func joinArgs(args ...interface{}) []string {
-	var final []string
-	for _, a := range args {
-		switch a.(type) {
-		case string:
-			final = append(final, a.(string))
-		case []string:
-			final = append(final, a.([]string)...)
-		default:
-			final = append(final, fmt.Sprint(a))
+	if ex {
+		if fex, _ := osutil.FileExists(pacman.FilesDatabasePath(dbpath)); !fex {
+			term.Warnf("Warning: files database missing, rebuilding file lists from package files\n")
 		}
 	}
-	return final
-}
-
-// system runs cmd, and prints the stderr output to ew, if ew is not nil.
-func (r *Repo) system(cmd *exec.Cmd) error {
-	command := strings.Join(cmd.Args, " ")
-	term.Debugf("Executing: %s\n", command)
-
-	bs, err := cmd.CombinedOutput()
-	if err != nil {
-		term.Errorf("Error executing: %s\n", command)
-		term.Errorff("---\n")
-		term.Errorff("%s", bs)
-		term.Errorff("...\n")
-		return fmt.Errorf("command exited with non-zero return code: %s", command)
-	}
-	return nil
-}
-
-// in performs a function in a directory, and then returns to the
-// previous directory.
-func in(dir string, f func() error) (err error) {
-	cwd, err := os.Getwd()
+	db, err := pacman.OpenDatabaseWriter(dbpath)
 	if err != nil {
 		return err
 	}
-	err = os.Chdir(dir)
-	if err != nil {
-		return err
+	defer db.Close()
+	for _, p := range remove {
+		term.Printf("Removing package from database: %s\n", p)
+		if !db.Remove(p) {
+			term.Warnf("Warning: package not found in database: %s\n", p)
+		}
 	}
-	defer func() {
-		cerr := os.Chdir(cwd)
-		if err == nil {
-			err = cerr
+	for _, p := range add {
+		term.Printf("Adding package to database: %s\n", p)
+		if _, err := db.Add(p); err != nil {
+			return err
 		}
-	}()
-	err = f()
-	return
+	}
+	if err := db.Commit(); err != nil {
+		return err
+	}
+	return r.SignDatabase()
 }
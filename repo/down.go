@@ -8,18 +8,35 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 
 	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
 	"github.com/cassava/repoctl/pacman/aur"
 	"github.com/cassava/repoctl/pacman/graph"
 	"github.com/goulash/archive"
 	"github.com/goulash/osutil"
 )
 
-// DependencyGraph returns a dependency graph of the given package names.
-func DependencyGraph(pkgnames []string) (*graph.Graph, error) {
+// GraphOptions controls which packages DependencyGraph puts in the graph.
+type GraphOptions struct {
+	// Prefer contains the names of packages that are preferred when
+	// a dependency is provided by several packages.
+	Prefer []string
+	// Installed includes installed packages, which are otherwise left
+	// out because they need not be built.
+	Installed bool
+	// Full resolves the dependencies of packages from the repositories
+	// as well, which are otherwise left to pacman.
+	Full bool
+}
+
+// DependencyGraph returns a dependency graph of the given package names,
+// where dependencies are resolved against the pacman system sys.
+//
+// If some dependencies cannot be satisfied, the graph is returned
+// together with graph.UnsatisfiedErrors.
+func DependencyGraph(sys *pacman.System, opts GraphOptions, pkgnames []string) (*graph.Graph, error) {
 	aurpkgs, err := aur.ReadAll(pkgnames)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read AUR: %w", err)
@@ -27,13 +44,14 @@ func DependencyGraph(pkgnames []string) (*graph.Graph, error) {
 
 	// Get dependencies
 	term.Debugf("Creating dependency graph ...\n")
-	f, err := graph.NewFactory()
+	f, err := graph.NewFactory(sys)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create dependency graph: %w", err)
 	}
 
-	f.SetSkipInstalled(true)
-	f.SetTruncate(true)
+	f.SetSkipInstalled(!opts.Installed)
+	f.SetTruncate(!opts.Full)
+	f.SetPreferredProviders(opts.Prefer...)
 	return f.NewGraph(uniqueBases(aurpkgs))
 }
 
@@ -95,13 +113,13 @@ func DownloadExtractAUR(ap *aur.Package, destdir string, clobber bool) error {
 	}
 
 	term.Debugf("Fetching URL: %s\n", ap.DownloadURL())
-	response, err := http.Get(ap.DownloadURL())
+	body, err := aur.DefaultClient.Snapshot(aur.DefaultContext, ap)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
+	defer body.Close()
 
-	gr, err := gzip.NewReader(response.Body)
+	gr, err := gzip.NewReader(body)
 	if err != nil {
 		return err
 	}
@@ -139,11 +157,11 @@ func DownloadTarballAUR(ap *aur.Package, destdir string, clobber bool) error {
 	}
 
 	term.Debugf("Fetching URL: %s\n", url)
-	response, err := http.Get(url)
+	body, err := aur.DefaultClient.Snapshot(aur.DefaultContext, ap)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
+	defer body.Close()
 
 	file, err := os.Create(of)
 	if err != nil {
@@ -151,7 +169,7 @@ func DownloadTarballAUR(ap *aur.Package, destdir string, clobber bool) error {
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, response.Body)
+	_, err = io.Copy(file, body)
 	if err != nil {
 		// Should I delete?
 		return err
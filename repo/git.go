@@ -0,0 +1,202 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman/aur"
+	"github.com/goulash/osutil"
+)
+
+// ErrNotFastForward is returned when a git checkout of a package has
+// commits or changes that are not in the remote, so that it cannot
+// simply be updated.
+var ErrNotFastForward = errors.New("checkout cannot be fast-forwarded")
+
+// GitRemote returns the URL of the git repository of the package base,
+// where %s in remote is replaced by the package base, such as in
+// "/srv/aur/%s.git". If remote is empty, the repository on the AUR of
+// aur.DefaultClient is used.
+func GitRemote(remote, pkgbase string) string {
+	if remote == "" {
+		remote = aur.DefaultClient.BaseURL + "/%s.git"
+	}
+	return strings.ReplaceAll(remote, "%s", pkgbase)
+}
+
+// DownloadGit clones the git repositories of the given packages, or
+// updates them if they have been cloned before. See DownloadGitAUR.
+//
+// If a package cannot be found, it will be reported, but
+// the rest of the packages will be downloaded.
+func DownloadGit(destdir string, remote string, clobber bool, pkgnames []string) error {
+	if len(pkgnames) == 0 {
+		return nil
+	}
+
+	aurpkgs, err := aur.ReadAll(pkgnames)
+	if err != nil {
+		term.Errorf("Error: %s\n", err)
+	}
+	return DownloadGitPackages(uniqueBases(aurpkgs), destdir, remote, clobber)
+}
+
+// DownloadGitPackages clones or updates the git repositories of the given
+// AUR packages, printing messages for each one.
+func DownloadGitPackages(pkgs aur.Packages, destdir string, remote string, clobber bool) error {
+	for _, p := range pkgs {
+		err := DownloadGitAUR(p, destdir, remote, clobber)
+		if err != nil {
+			term.Errorf("Error: %s: %s\n", p.PackageBase, err)
+		}
+	}
+	return nil
+}
+
+// DownloadGitAUR clones the git repository of the package base of ap into
+// destdir. If it has been cloned before, the new commits are fetched and
+// the checkout is fast-forwarded; the previous HEAD is kept in ORIG_HEAD,
+// so that the changes can be reviewed with git log -p ORIG_HEAD..HEAD.
+//
+// If the directory exists but is not a git repository, it is replaced if
+// clobber is true, and otherwise ErrPkgDirExists is returned. In offline
+// mode, aur.ErrOffline is returned.
+func DownloadGitAUR(ap *aur.Package, destdir string, remote string, clobber bool) error {
+	if aur.DefaultClient.Offline {
+		return aur.ErrOffline
+	}
+
+	var err error
+	if destdir == "" {
+		destdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+	base := ap.PackageBase
+	if base == "" {
+		base = ap.Name
+	}
+	dir := filepath.Join(destdir, base)
+	url := GitRemote(remote, base)
+
+	ex, err := osutil.DirExists(dir)
+	if err != nil {
+		return err
+	}
+	if ex {
+		isGit, err := osutil.DirExists(filepath.Join(dir, ".git"))
+		if err != nil {
+			return err
+		}
+		if isGit {
+			return updateGit(base, dir)
+		}
+		if !clobber {
+			return ErrPkgDirExists
+		}
+		term.Debugf("Removing directory: %s\n", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	term.Printf("Cloning: %s\n", base)
+	term.Debugf("Cloning repository: %s\n", url)
+	_, err = git("", "clone", "--quiet", url, dir)
+	return err
+}
+
+// updateGit fetches the new commits of the repository in dir, and
+// fast-forwards the checkout to them.
+func updateGit(base, dir string) error {
+	old, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	term.Debugf("Fetching repository: %s\n", dir)
+	if _, err := git(dir, "fetch", "--quiet"); err != nil {
+		return err
+	}
+	upstream, err := git(dir, "rev-parse", "@{upstream}")
+	if err != nil {
+		return err
+	}
+	if upstream == old {
+		term.Printf("Up-to-date: %s\n", base)
+		return nil
+	}
+	ff, err := isAncestor(dir, "HEAD", "@{upstream}")
+	if err != nil {
+		return err
+	}
+	if !ff {
+		return ErrNotFastForward
+	}
+	if _, err := git(dir, "merge", "--quiet", "--ff-only", "@{upstream}"); err != nil {
+		return fmt.Errorf("%w: %s", ErrNotFastForward, err)
+	}
+	if _, err := git(dir, "update-ref", "ORIG_HEAD", old); err != nil {
+		return err
+	}
+	term.Printf("Updating: %s %s..%s\n", base, shortHash(old), shortHash(upstream))
+	term.Printf("  Review with: git -C %s log -p ORIG_HEAD..HEAD\n", dir)
+	return nil
+}
+
+// git runs git with the arguments in dir, or the current directory
+// if dir is empty, and returns the trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	bs, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(bs))
+	if err != nil {
+		return "", &gitError{Command: args[0], Output: out, Err: err}
+	}
+	return out, nil
+}
+
+// gitError is returned when git fails. It shows the output of git, which
+// explains the failure better than the exit status does.
+type gitError struct {
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *gitError) Error() string {
+	if e.Output != "" {
+		return fmt.Sprintf("git %s: %s", e.Command, e.Output)
+	}
+	return fmt.Sprintf("git %s: %s", e.Command, e.Err)
+}
+
+func (e *gitError) Unwrap() error { return e.Err }
+
+// isAncestor returns whether the commit a is an ancestor of the commit b
+// in the repository in dir.
+func isAncestor(dir, a, b string) (bool, error) {
+	_, err := git(dir, "merge-base", "--is-ancestor", a, b)
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func shortHash(s string) string {
+	if len(s) > 8 {
+		return s[:8]
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman/aur"
+)
+
+// newTestRemote creates a bare git repository for the package base foo in
+// a temporary directory, and returns the remote for DownloadGitAUR and a
+// checkout that commits can be pushed to the repository from.
+func newTestRemote(t *testing.T) (remote, work string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	work = filepath.Join(dir, "work")
+	mustGit(t, "", "init", "--quiet", work)
+	commitFile(t, work, "PKGBUILD", "pkgver=1.0\n")
+	mustGit(t, "", "clone", "--quiet", "--bare", work, filepath.Join(dir, "foo.git"))
+	mustGit(t, work, "remote", "add", "origin", filepath.Join(dir, "foo.git"))
+	return filepath.Join(dir, "%s.git"), work
+}
+
+func mustGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return out
+}
+
+// commitFile writes the file in the checkout dir and commits it.
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mustGit(t, dir, "add", name)
+	mustGit(t, dir, "commit", "--quiet", "-m", "Update "+name)
+}
+
+func TestDownloadGitAUR(t *testing.T) {
+	remote, work := newTestRemote(t)
+	dest := t.TempDir()
+	dir := filepath.Join(dest, "foo")
+	ap := &aur.Package{Name: "foo", PackageBase: "foo"}
+
+	if err := DownloadGitAUR(ap, dest, remote, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bs, err := os.ReadFile(filepath.Join(dir, "PKGBUILD")); err != nil || string(bs) != "pkgver=1.0\n" {
+		t.Fatalf("expected PKGBUILD to be cloned, got %q, %v", bs, err)
+	}
+	old := mustGit(t, dir, "rev-parse", "HEAD")
+
+	// Nothing changed upstream.
+	if err := DownloadGitAUR(ap, dest, remote, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// New commits are fast-forwarded, and the old HEAD is kept.
+	commitFile(t, work, "PKGBUILD", "pkgver=1.1\n")
+	mustGit(t, work, "push", "--quiet", "origin", "HEAD")
+	if err := DownloadGitAUR(ap, dest, remote, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bs, _ := os.ReadFile(filepath.Join(dir, "PKGBUILD")); string(bs) != "pkgver=1.1\n" {
+		t.Errorf("expected PKGBUILD to be updated, got %q", bs)
+	}
+	if h := mustGit(t, dir, "rev-parse", "HEAD"); h != mustGit(t, work, "rev-parse", "HEAD") {
+		t.Errorf("expected HEAD to be fast-forwarded to upstream")
+	}
+	if h := mustGit(t, dir, "rev-parse", "ORIG_HEAD"); h != old {
+		t.Errorf("expected ORIG_HEAD %s, got %s", old, h)
+	}
+
+	// Local commits are not overwritten.
+	commitFile(t, dir, "local.patch", "patch\n")
+	commitFile(t, work, "PKGBUILD", "pkgver=1.2\n")
+	mustGit(t, work, "push", "--quiet", "origin", "HEAD")
+	if err := DownloadGitAUR(ap, dest, remote, false); !errors.Is(err, ErrNotFastForward) {
+		t.Errorf("expected ErrNotFastForward, got %v", err)
+	}
+	if bs, _ := os.ReadFile(filepath.Join(dir, "local.patch")); string(bs) != "patch\n" {
+		t.Errorf("expected local commit to be kept")
+	}
+}
+
+func TestDownloadGitAURExists(t *testing.T) {
+	remote, _ := newTestRemote(t)
+	dest := t.TempDir()
+	ap := &aur.Package{Name: "foo", PackageBase: "foo"}
+	os.MkdirAll(filepath.Join(dest, "foo"), 0755)
+
+	if err := DownloadGitAUR(ap, dest, remote, false); err != ErrPkgDirExists {
+		t.Errorf("expected ErrPkgDirExists, got %v", err)
+	}
+	if err := DownloadGitAUR(ap, dest, remote, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "foo", ".git")); err != nil {
+		t.Errorf("expected directory to be replaced by a clone: %s", err)
+	}
+}
+
+func TestDownloadGitAUROffline(t *testing.T) {
+	remote, _ := newTestRemote(t)
+	dest := t.TempDir()
+	ap := &aur.Package{Name: "foo", PackageBase: "foo"}
+
+	aur.DefaultClient.Offline = true
+	defer func() { aur.DefaultClient.Offline = false }()
+	if err := DownloadGitAUR(ap, dest, remote, false); !errors.Is(err, aur.ErrOffline) {
+		t.Errorf("expected ErrOffline, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "foo")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be cloned")
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	_, work := newTestRemote(t)
+	commitFile(t, work, "PKGBUILD", "pkgver=1.1\n")
+
+	if ok, err := isAncestor(work, "HEAD~1", "HEAD"); err != nil || !ok {
+		t.Errorf("expected HEAD~1 to be an ancestor of HEAD, got %v, %v", ok, err)
+	}
+	if ok, err := isAncestor(work, "HEAD", "HEAD~1"); err != nil || ok {
+		t.Errorf("expected HEAD not to be an ancestor of HEAD~1, got %v, %v", ok, err)
+	}
+	// Failures of git are errors, not a missing ancestor.
+	if _, err := isAncestor(work, "HEAD", "no-such-branch"); err == nil {
+		t.Errorf("expected error for unknown revision")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman/pgp"
+)
+
+// ReadKeyring reads the keyring that package signatures are verified
+// against. If the repository has no keyring configured, or the keyring
+// does not exist yet, an empty keyring is returned.
+func (r *Repo) ReadKeyring() (*pgp.Keyring, error) {
+	return pgp.ReadKeyring(r.Keyring)
+}
+
+// VerifySignature checks the signature of the package against the keyring.
+//
+// If the package has no signature, an error is only returned if a
+// signature is required. If the keyring is empty, signatures cannot be
+// verified, which is an error only if a signature is required. Otherwise,
+// the signature must be valid and be made by a key in the keyring.
+func (r *Repo) VerifySignature(kr *pgp.Keyring, pkg *SignedPkg) error {
+	if !pkg.HasSignature() {
+		if r.RequireSignature {
+			return errors.New("require signature but none available")
+		}
+		return nil
+	}
+	if kr.Len() == 0 {
+		if r.RequireSignature {
+			return fmt.Errorf("require signature but keyring is empty (import keys with the keys command)")
+		}
+		term.Debugf("Cannot verify signature without keyring: %s\n", pkg.SigFile)
+		return nil
+	}
+
+	key, err := kr.VerifyFile(pkg.PkgFile)
+	if err != nil {
+		return err
+	}
+	term.Printf("Good signature from %s: %s\n", key, pkg.SigFile)
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	"github.com/goulash/errs"
+)
+
+// ErrRequired is returned by RemoveChecked when packages that are to be
+// removed are required by others in the repository.
+var ErrRequired = errors.New("packages are required by others")
+
+// Dependent is a package in the repository that depends on another.
+type Dependent struct {
+	// Package is the package that has the dependency.
+	Package *pacman.Package
+	// On is the name of the package that satisfies the dependency.
+	On string
+	// Depend is the dependency, which On satisfies either by name or
+	// by what it provides.
+	Depend alpm.Depend
+}
+
+// ReverseDependencies returns the packages in the database that depend on
+// any of the packages named, either by name or by what they provide, and
+// that would be broken if these were removed. A dependency that is also
+// satisfied by a package that stays in the repository, such as foo-bin
+// providing foo, does not count. The packages named are not part of the
+// result, and neither are dependencies on them through other packages,
+// unless recursive is true.
+//
+// The result is sorted by the name of the dependent package.
+func (r *Repo) ReverseDependencies(recursive bool, pkgnames ...string) ([]Dependent, error) {
+	pkgs, err := r.ReadDatabase()
+	if err != nil {
+		return nil, err
+	}
+	return reverseDependencies(pkgs, recursive, pkgnames), nil
+}
+
+// RemoveChecked removes the packages like Remove, unless other packages in
+// the repository depend on them; see ReverseDependencies. Then these are
+// listed and ErrRequired is returned. If cascade is true, the dependent
+// packages are removed as well, and those that depend on them in turn.
+// If force is true, the packages are removed nonetheless, and dependent
+// packages are only listed as a warning. Cascade and force cannot both
+// be true.
+func (r *Repo) RemoveChecked(h errs.Handler, cascade, force bool, pkgnames ...string) error {
+	if cascade && force {
+		return errors.New("cannot cascade and force removal at the same time")
+	}
+	dependents, err := r.ReverseDependencies(cascade, pkgnames...)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range dependents {
+		switch {
+		case force:
+			term.Warnf("Warning: %s requires %s\n", d.Package.Name, d.Depend)
+		case cascade:
+			if !seen[d.Package.Name] {
+				seen[d.Package.Name] = true
+				pkgnames = append(pkgnames, d.Package.Name)
+				term.Printf("Also removing: %s (requires %s)\n", d.Package.Name, d.Depend)
+			}
+		default:
+			term.Errorf("Error: %s requires %s\n", d.Package.Name, d.Depend)
+		}
+	}
+	if len(dependents) != 0 && !cascade && !force {
+		return ErrRequired
+	}
+	return r.Remove(h, pkgnames...)
+}
+
+func reverseDependencies(pkgs pacman.Packages, recursive bool, pkgnames []string) []Dependent {
+	targets := make(map[string]bool)
+	removed := make(map[string]bool)
+	for _, name := range pkgnames {
+		targets[name] = true
+		removed[name] = true
+	}
+
+	// broken returns the removed packages that satisfy d, unless a package
+	// that stays in the repository satisfies it as well.
+	broken := func(d alpm.Depend) pacman.Packages {
+		var on pacman.Packages
+		for _, q := range pkgs {
+			if !q.Satisfies(d) {
+				continue
+			}
+			if !removed[q.Name] {
+				return nil
+			}
+			on = append(on, q)
+		}
+		return on
+	}
+
+	// Removing a package can break packages that still had another
+	// provider before, so continue until nothing changes.
+	for changed := recursive; changed; {
+		changed = false
+		for _, p := range pkgs {
+			if removed[p.Name] {
+				continue
+			}
+			for _, d := range alpm.ParseDepends(p.Depends) {
+				if len(broken(d)) != 0 {
+					removed[p.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var result []Dependent
+	for _, p := range pkgs {
+		if targets[p.Name] {
+			continue
+		}
+		for _, d := range alpm.ParseDepends(p.Depends) {
+			for _, t := range broken(d) {
+				if t.Name != p.Name {
+					result = append(result, Dependent{Package: p, On: t.Name, Depend: d})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Package.Name != result[j].Package.Name {
+			return result[i].Package.Name < result[j].Package.Name
+		}
+		return result[i].On < result[j].On
+	})
+	return result
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+)
+
+func TestReverseDependencies(t *testing.T) {
+	pkgs := pacman.Packages{
+		testPkg("libfoo", "1.0-1", "provides=libfoo.so=1-64"),
+		testPkg("foo", "1.0-1", "depends=libfoo.so=1-64"),
+		testPkg("foo-bin", "1.0-1", "provides=foo=1.0"),
+		testPkg("app", "1.0-1", "depends=foo>=1"),
+		testPkg("plugin", "1.0-1", "depends=app", "depends=libfoo"),
+		testPkg("bar", "2.0-1"),
+		testPkg("baz", "1.0-1", "depends=bar<2"),
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		remove    []string
+		want      []string
+	}{
+		{"by name", false, []string{"app"}, []string{"plugin app (app)"}},
+		{"by provides", false, []string{"libfoo"}, []string{"foo libfoo.so=1-64 (libfoo)", "plugin libfoo (libfoo)"}},
+		{"version", false, []string{"bar"}, nil},
+		{"still satisfied", false, []string{"foo"}, nil},
+		{"still satisfied recursive", true, []string{"libfoo"}, []string{"foo libfoo.so=1-64 (libfoo)", "plugin libfoo (libfoo)"}},
+		{"no longer satisfied", false, []string{"foo", "foo-bin"}, []string{"app foo>=1 (foo)", "app foo>=1 (foo-bin)"}},
+		{"recursive", true, []string{"foo", "foo-bin"}, []string{"app foo>=1 (foo)", "app foo>=1 (foo-bin)", "plugin app (app)"}},
+		{"unknown", true, []string{"typo"}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, d := range reverseDependencies(pkgs, tc.recursive, tc.remove) {
+				got = append(got, d.Package.Name+" "+d.Depend.String()+" ("+d.On+")")
+			}
+			if !equalStrings(got, tc.want) {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRemoveChecked(t *testing.T) {
+	tests := []struct {
+		cascade, force bool
+		err            bool
+		want           []string
+	}{
+		{false, false, true, []string{"app", "lib", "other"}},
+		{true, true, true, []string{"app", "lib", "other"}},
+		{false, true, false, []string{"app", "other"}},
+		{true, false, false, []string{"other"}},
+	}
+	for _, tc := range tests {
+		r := newTestRepo(t, nil, nil)
+		src := t.TempDir()
+		err := r.Copy(nil,
+			writeTestPackage(t, src, testPkg("lib", "1.0-1")),
+			writeTestPackage(t, src, testPkg("app", "1.0-1", "depends=lib")),
+			writeTestPackage(t, src, testPkg("other", "1.0-1")),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		err = r.RemoveChecked(nil, tc.cascade, tc.force, "lib")
+		if (err != nil) != tc.err {
+			t.Errorf("cascade %v, force %v: unexpected error: %v", tc.cascade, tc.force, err)
+		}
+		if !tc.cascade && !tc.force && !errors.Is(err, ErrRequired) {
+			t.Errorf("expected ErrRequired, got %v", err)
+		}
+		pkgs, err := r.ReadDatabase()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		if !equalStrings(names, tc.want) {
+			t.Errorf("cascade %v, force %v: expected %v in database, got %v", tc.cascade, tc.force, tc.want, names)
+		}
+	}
+}
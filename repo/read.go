@@ -32,8 +32,22 @@ func (r *Repo) ReadDatabase() (pacman.Packages, error) {
 	return pkgs, err
 }
 
+// ReadFilesDatabase reads the files database that belongs to the
+// repository database. If it does not exist, an error is returned.
+func (r *Repo) ReadFilesDatabase() ([]*pacman.PackageFiles, error) {
+	pkgs, err := pacman.ReadFilesDatabase(r.FilesDatabasePath())
+	list := make(pacman.Packages, len(pkgs))
+	for i, p := range pkgs {
+		list[i] = p.Package
+	}
+	r.MakeAbs(list)
+	return pkgs, err
+}
+
+// ReadDir reads all packages in the repository directory, using the
+// metadata cache if there is one.
 func (r *Repo) ReadDir(h errs.Handler) (pacman.Packages, error) {
-	pkgs, err := pacman.ReadDir(h, r.Directory, r.DatabasePath())
+	pkgs, err := pacman.ReadDir(h, r.Directory, r.DatabasePath(), r.readCache())
 	r.MakeAbs(pkgs)
 	return pkgs, err
 }
@@ -58,7 +72,7 @@ func (r *Repo) ReadAllNames(h errs.Handler) (pacman.Packages, error) {
 func (r *Repo) ReadMeta(h errs.Handler, pkgnames ...string) (meta.Packages, error) {
 	errs.Init(&h)
 
-	pkgs, err := meta.Read(h, r.Directory, r.DatabasePath())
+	pkgs, err := meta.Read(h, r.Directory, r.DatabasePath(), r.readCache())
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +82,21 @@ func (r *Repo) ReadMeta(h errs.Handler, pkgnames ...string) (meta.Packages, erro
 	return pu.Filter(pkgs, pu.NameFltr(pkgnames)).(meta.Packages), nil
 }
 
+// readCache returns the metadata cache of the repository, or nil if
+// there is none. A cache that cannot be read is ignored, since it is
+// only an optimization.
+func (r *Repo) readCache() *pacman.Cache {
+	if r.Cache == "" {
+		return nil
+	}
+	c, err := pacman.ReadCache(r.Cache)
+	if err != nil {
+		term.Debugf("Warning: %s\n", err)
+		return nil
+	}
+	return c
+}
+
 // ReadAUR reads the given package names from AUR. If no package names
 // are given, ReadAUR reads all the names found in the repository.
 //
@@ -0,0 +1,76 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	pu "github.com/cassava/repoctl/pacman/pkgutil"
+)
+
+// Rebuild is a package in the repository that needs to be rebuilt,
+// because a shared library that it was linked against is no longer
+// provided in the version that it requires.
+type Rebuild struct {
+	Package *pacman.Package
+	// Depend is the versioned soname dependency, such as libfoo.so=3-64.
+	Depend alpm.Depend
+	// Available are the versions of the library that are provided instead,
+	// such as "libfoo.so=4-64 (extra/libfoo 4.0-1)". It is empty if the
+	// library is not provided at all anymore.
+	Available []string
+}
+
+// FindRebuilds returns the packages in the repository that depend on a
+// version of a shared library that neither the repository nor the sync
+// repositories of the pacman system provide anymore. If pkgnames is empty,
+// all packages in the database are considered.
+//
+// Only dependencies on sonames with a version, such as libfoo.so=3-64,
+// are considered. makepkg adds these to packages automatically, and the
+// version changes whenever the library breaks binary compatibility.
+func (r *Repo) FindRebuilds(pkgnames ...string) ([]Rebuild, error) {
+	pkgs, err := r.ReadDatabase()
+	if err != nil {
+		return nil, err
+	}
+	sync, err := r.readSyncIndex()
+	if err != nil {
+		return nil, err
+	}
+	sync.add(r.Name(), pkgs)
+
+	if len(pkgnames) != 0 {
+		pkgs = pu.Filter(pkgs, pu.NameFltr(pkgnames)).(pacman.Packages)
+	}
+	var result []Rebuild
+	for _, p := range pkgs {
+		for _, d := range alpm.ParseDepends(p.Depends) {
+			if !d.IsLibrary() || !d.IsVersioned() || sync.satisfies(d) {
+				continue
+			}
+			result = append(result, Rebuild{
+				Package:   p,
+				Depend:    d,
+				Available: sync.provisions(d.Name),
+			})
+		}
+	}
+	return result, nil
+}
+
+// provisions returns what the packages in the index provide for name,
+// together with the package, such as "libfoo.so=4-64 (extra/libfoo 4.0-1)".
+func (x *syncIndex) provisions(name string) []string {
+	var result []string
+	for _, e := range x.byProvides[name] {
+		for _, s := range e.pkg.Provides {
+			if alpm.ParseDepend(s).Name == name {
+				result = append(result, s+" ("+e.String()+")")
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+)
+
+func TestFindRebuilds(t *testing.T) {
+	pkgs := pacman.Packages{
+		// libfoo was bumped to a new soname, which app was not rebuilt for.
+		testPkg("libfoo", "4.0-1", "provides=libfoo.so=4-64"),
+		testPkg("app", "1.0-1", "depends=libfoo", "depends=libfoo.so=3-64", "depends=libc.so=6-64"),
+		testPkg("tool", "1.0-1", "depends=libfoo.so=4-64", "depends=libbar.so=1-64"),
+		// libgone was removed from extra, and nothing provides it anymore.
+		testPkg("legacy", "1.0-1", "depends=libgone.so=2-64", "depends=libgone.so"),
+		// Unversioned dependencies on libraries are not considered.
+		testPkg("other", "1.0-1", "depends=libmissing.so"),
+	}
+	sync := map[string]pacman.Packages{
+		"core":  {testPkg("glibc", "2.38-7", "provides=libc.so=6-64")},
+		"extra": {testPkg("libbar", "1.2-1", "provides=libbar.so=1-64")},
+	}
+	r := newTestRepo(t, pkgs, sync)
+
+	rebuilds, err := r.FindRebuilds()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got []string
+	for _, x := range rebuilds {
+		got = append(got, x.Package.Name+" "+x.Depend.String()+": "+strings.Join(x.Available, ", "))
+	}
+	want := []string{
+		"app libfoo.so=3-64: libfoo.so=4-64 (test/libfoo 4.0-1)",
+		"legacy libgone.so=2-64: ",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("expected rebuilds %q, got %q", want, got)
+	}
+
+	// A bump in the sync repositories affects packages in the repository.
+	sync["extra"] = pacman.Packages{testPkg("libbar", "2.0-1", "provides=libbar.so=2-64")}
+	r = newTestRepo(t, pkgs, sync)
+	rebuilds, err = r.FindRebuilds("tool", "other")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rebuilds) != 1 || rebuilds[0].Package.Name != "tool" || rebuilds[0].Depend.String() != "libbar.so=1-64" ||
+		!equalStrings(rebuilds[0].Available, []string{"libbar.so=2-64 (extra/libbar 2.0-1)"}) {
+		t.Errorf("unexpected rebuilds for tool: %+v", rebuilds)
+	}
+}
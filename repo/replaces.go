@@ -0,0 +1,165 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/alpm"
+	pu "github.com/cassava/repoctl/pacman/pkgutil"
+)
+
+// ReplacePolicy specifies what happens when a package is added to the
+// repository that replaces or conflicts with a package that is already
+// in the repository, such as when foo is renamed to foo-bin.
+type ReplacePolicy string
+
+const (
+	// ReplaceWarn adds the package, but warns that the old package remains.
+	ReplaceWarn ReplacePolicy = "warn"
+	// ReplaceRemove removes the old package, as Remove does.
+	ReplaceRemove ReplacePolicy = "remove"
+	// ReplaceRefuse does not add the package.
+	ReplaceRefuse ReplacePolicy = "refuse"
+)
+
+// replacement is a package in the database that an incoming package
+// replaces or conflicts with.
+type replacement struct {
+	old    *pacman.Package
+	reason string
+	depend alpm.Depend
+}
+
+func (x replacement) String() string {
+	return fmt.Sprintf("%s %s (%s)", x.reason, x.old.Name, x.depend)
+}
+
+// findReplaced returns the packages in db that p replaces or conflicts
+// with. Packages with the same name as p are not considered, since p is
+// simply a new version of them.
+func findReplaced(db pacman.Packages, p *pacman.Package) []replacement {
+	var result []replacement
+	seen := make(map[string]bool)
+	add := func(old *pacman.Package, reason string, d alpm.Depend) {
+		if old.Name == p.Name || seen[old.Name] {
+			return
+		}
+		seen[old.Name] = true
+		result = append(result, replacement{old, reason, d})
+	}
+
+	// As in pacman, replaces only match by name, not by what a package provides.
+	replaces := alpm.ParseDepends(p.Replaces)
+	conflicts := alpm.ParseDepends(p.Conflicts)
+	for _, old := range db {
+		for _, d := range replaces {
+			if d.Name == old.Name && d.Op.Compare(old.Version, d.Version) {
+				add(old, "replaces", d)
+			}
+		}
+		for _, d := range conflicts {
+			if old.Satisfies(d) {
+				add(old, "conflicts with", d)
+			}
+		}
+		for _, d := range alpm.ParseDepends(old.Conflicts) {
+			if p.Satisfies(d) {
+				add(old, "conflicts with", d)
+			}
+		}
+	}
+	return result
+}
+
+// applyReplacePolicy finds the packages in db that p replaces or conflicts
+// with, and applies the replace policy of the repository to them.
+//
+// It returns false if p should not be added, and otherwise the packages
+// that should be removed from the database.
+func (r *Repo) applyReplacePolicy(db pacman.Packages, p *pacman.Package) (bool, pacman.Packages) {
+	xs := findReplaced(db, p)
+	if len(xs) == 0 {
+		return true, nil
+	}
+
+	switch r.ReplacePolicy {
+	case ReplaceRefuse:
+		for _, x := range xs {
+			term.Errorf("Skipping %s: %s\n", p.Filename, x)
+		}
+		return false, nil
+	case ReplaceRemove:
+		remove := make(pacman.Packages, len(xs))
+		for i, x := range xs {
+			term.Printf("Replacing package: %s %s\n", p.Name, x)
+			remove[i] = x.old
+		}
+		return true, remove
+	default:
+		for _, x := range xs {
+			term.Warnf("Warning: %s %s, which remains in the repository\n", p.Name, x)
+		}
+		return true, nil
+	}
+}
+
+// replaceBatch tracks what the database will be after the packages of one
+// batch have been added, so that the packages of a batch are also checked
+// against each other, and not only against the database.
+type replaceBatch struct {
+	db       map[string]*pacman.Package
+	current  pacman.Packages
+	added    map[*pacman.Package]bool
+	replaced map[string]string
+}
+
+func newReplaceBatch(db pacman.Packages) *replaceBatch {
+	return &replaceBatch{
+		db:       db.ToMap(),
+		current:  append(pacman.Packages(nil), db...),
+		added:    make(map[*pacman.Package]bool),
+		replaced: make(map[string]string),
+	}
+}
+
+// replacedBy returns the name of the package that replaced the package
+// name in this batch, or "" if it has not been replaced. Such a package
+// must not be added again.
+func (b *replaceBatch) replacedBy(name string) string {
+	return b.replaced[name]
+}
+
+// check applies the replace policy of r to p, as applyReplacePolicy does.
+func (b *replaceBatch) check(r *Repo, p *pacman.Package) (bool, pacman.Packages) {
+	return r.applyReplacePolicy(b.current, p)
+}
+
+// accept records that p is added and that the packages in remove, which
+// check returned, are removed. It returns the packages that need to be
+// removed from the database, and the packages of the batch that must no
+// longer be added. For the latter, the version in the database is removed
+// as well.
+func (b *replaceBatch) accept(p *pacman.Package, remove pacman.Packages) (fromDB, fromBatch pacman.Packages) {
+	for _, x := range remove {
+		b.replaced[x.Name] = p.Name
+		if !b.added[x] {
+			fromDB = append(fromDB, x)
+			continue
+		}
+		fromBatch = append(fromBatch, x)
+		if old, ok := b.db[x.Name]; ok {
+			fromDB = append(fromDB, old)
+		}
+	}
+	b.added[p] = true
+	b.current = pu.Filter(b.current, func(q pacman.AnyPackage) bool {
+		return q.PkgName() != p.Name && !containsPkg(remove, q.Pkg())
+	}).(pacman.Packages)
+	b.current = append(b.current, p)
+	return fromDB, fromBatch
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cassava/repoctl/pacman"
+)
+
+// writeTestPackage creates a package file for p in dir and returns its path.
+func writeTestPackage(t *testing.T, dir string, p *pacman.Package) string {
+	t.Helper()
+	if p.Arch == "" {
+		p.Arch = "any"
+	}
+	pkginfo := pacman.MarshalPkgInfo(p)
+	path := filepath.Join(dir, p.Name+"-"+p.Version+"-"+p.Arch+".pkg.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	err = tw.WriteHeader(&tar.Header{Name: ".PKGINFO", Mode: 0644, Size: int64(len(pkginfo))})
+	if err == nil {
+		_, err = tw.Write(pkginfo)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return path
+}
+
+func TestFindReplaced(t *testing.T) {
+	db := pacman.Packages{
+		testPkg("foo", "1.0-1"),
+		testPkg("foo-git", "r10-1", "provides=foo=1.1", "conflicts=foo"),
+		testPkg("bar", "2.0-1", "conflicts=baz<2"),
+		testPkg("libqux", "1.0-1", "provides=qux"),
+	}
+	tests := []struct {
+		pkg  *pacman.Package
+		want []string
+	}{
+		{testPkg("foo", "1.1-1"), []string{"conflicts with foo-git (foo)"}},
+		{testPkg("foo-bin", "1.1-1", "replaces=foo"), []string{"replaces foo (foo)"}},
+		{testPkg("foo-bin", "1.1-1", "replaces=foo<1"), nil},
+		{testPkg("foo-bin", "1.1-1", "provides=foo=1.1", "conflicts=foo", "replaces=foo"), []string{"replaces foo (foo)", "conflicts with foo-git (foo)"}},
+		{testPkg("baz", "1.0-1"), []string{"conflicts with bar (baz<2)"}},
+		{testPkg("baz", "2.0-1"), nil},
+		// Replaces only match by name, conflicts also by provides.
+		{testPkg("qux-ng", "1.0-1", "replaces=qux"), nil},
+		{testPkg("qux-ng", "1.0-1", "conflicts=qux"), []string{"conflicts with libqux (qux)"}},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, x := range findReplaced(db, tc.pkg) {
+			got = append(got, x.String())
+		}
+		if !equalStrings(got, tc.want) {
+			t.Errorf("%s %v %v: expected %q, got %q", tc.pkg.Name, tc.pkg.Replaces, tc.pkg.Conflicts, tc.want, got)
+		}
+	}
+}
+
+func TestApplyReplacePolicy(t *testing.T) {
+	foo := testPkg("foo", "1.0-1")
+	db := pacman.Packages{foo, testPkg("bar", "1.0-1")}
+	pkg := testPkg("foo-bin", "1.1-1", "replaces=foo")
+	other := testPkg("baz", "1.0-1")
+
+	tests := []struct {
+		policy ReplacePolicy
+		ok     bool
+		remove pacman.Packages
+	}{
+		{ReplaceWarn, true, nil},
+		{"", true, nil},
+		{ReplaceRemove, true, pacman.Packages{foo}},
+		{ReplaceRefuse, false, nil},
+	}
+	for _, tc := range tests {
+		r := &Repo{ReplacePolicy: tc.policy}
+		ok, remove := r.applyReplacePolicy(db, pkg)
+		if ok != tc.ok || len(remove) != len(tc.remove) || (len(remove) != 0 && remove[0] != tc.remove[0]) {
+			t.Errorf("%q: expected %v and %v, got %v and %v", tc.policy, tc.ok, tc.remove, ok, remove)
+		}
+		// Packages that replace nothing are always added.
+		if ok, remove := r.applyReplacePolicy(db, other); !ok || len(remove) != 0 {
+			t.Errorf("%q: expected unrelated package to be added, got %v and %v", tc.policy, ok, remove)
+		}
+	}
+}
+
+func TestAddReplacesBatch(t *testing.T) {
+	tests := []struct {
+		policy ReplacePolicy
+		want   []string
+	}{
+		{ReplaceWarn, []string{"foo", "foo-bin", "foo-git"}},
+		{ReplaceRemove, []string{"foo-git"}},
+		{ReplaceRefuse, []string{"foo", "foo-git"}},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			r := newTestRepo(t, nil, nil)
+			r.ReplacePolicy = tc.policy
+			os.MkdirAll(r.Directory, 0755)
+			src := t.TempDir()
+			if err := r.Copy(nil, writeTestPackage(t, src, testPkg("foo", "1.0-1"))); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// foo-bin replaces foo, which is in the database, and foo-git
+			// conflicts with foo-bin, which is only in the same batch.
+			err := r.Copy(nil,
+				writeTestPackage(t, src, testPkg("foo-bin", "1.1-1", "replaces=foo")),
+				writeTestPackage(t, src, testPkg("foo-git", "r10-1", "conflicts=foo-bin")),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			pkgs, err := r.ReadDatabase()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var names []string
+			for _, p := range pkgs {
+				names = append(names, p.Name)
+			}
+			sort.Strings(names)
+			if !equalStrings(names, tc.want) {
+				t.Errorf("expected %v in database, got %v", tc.want, names)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(r.Directory, "*.pkg.tar.gz"))
+			if len(files) != len(tc.want) {
+				t.Errorf("expected %d package files in repository, got %v", len(tc.want), files)
+			}
+		})
+	}
+}
+
+func TestUpdateReplacesBatch(t *testing.T) {
+	tests := []struct {
+		policy ReplacePolicy
+		pkgs   []*pacman.Package
+		want   []string
+		files  []string
+	}{
+		// foo is updated and replaced in the same run, in either order.
+		{ReplaceRemove, []*pacman.Package{
+			testPkg("foo", "1.1-1"),
+			testPkg("foo-bin", "1.1-1", "replaces=foo"),
+		}, []string{"foo-bin"}, []string{"foo-bin-1.1-1"}},
+		{ReplaceRemove, []*pacman.Package{
+			testPkg("bar", "1.1-1", "replaces=foo"),
+			testPkg("foo", "1.1-1"),
+		}, []string{"bar"}, []string{"bar-1.1-1"}},
+		// New packages are checked against each other.
+		{ReplaceRemove, []*pacman.Package{
+			testPkg("foo-bin", "1.1-1", "replaces=foo"),
+			testPkg("foo-git", "r10-1", "conflicts=foo-bin"),
+		}, []string{"foo-git"}, []string{"foo-git-r10-1"}},
+		{ReplaceRefuse, []*pacman.Package{
+			testPkg("foo", "1.1-1"),
+			testPkg("foo-bin", "1.1-1", "replaces=foo"),
+		}, []string{"foo"}, []string{"foo-1.1-1", "foo-bin-1.1-1"}},
+		{ReplaceWarn, []*pacman.Package{
+			testPkg("foo", "1.1-1"),
+			testPkg("foo-bin", "1.1-1", "replaces=foo"),
+		}, []string{"foo", "foo-bin"}, []string{"foo-1.1-1", "foo-bin-1.1-1"}},
+	}
+	for _, tc := range tests {
+		r := newTestRepo(t, nil, nil)
+		r.ReplacePolicy = tc.policy
+		if err := r.Copy(nil, writeTestPackage(t, t.TempDir(), testPkg("foo", "1.0-1"))); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, p := range tc.pkgs {
+			writeTestPackage(t, r.Directory, p)
+		}
+		if err := r.Update(nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		pkgs, err := r.ReadDatabase()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		sort.Strings(names)
+		if !equalStrings(names, tc.want) {
+			t.Errorf("%s %s: expected %v in database, got %v", tc.policy, tc.pkgs[0].Name, tc.want, names)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(r.Directory, "*.pkg.tar.gz"))
+		for i, f := range files {
+			files[i] = strings.TrimSuffix(filepath.Base(f), "-any.pkg.tar.gz")
+		}
+		if !equalStrings(files, tc.files) {
+			t.Errorf("%s %s: expected files %v in repository, got %v", tc.policy, tc.pkgs[0].Name, tc.files, files)
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"strings"
 
 	"github.com/cassava/repoctl/conf"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/pgp"
 	"github.com/cassava/repoctl/pacman/pkgutil"
 	"github.com/goulash/osutil"
 )
@@ -26,6 +28,14 @@ type Repo struct {
 
 	// RequireSignature specifies whether files can be added without signature.
 	RequireSignature bool
+	// Keyring is the path to the keyring that package signatures are
+	// verified against. If empty, signatures are not verified.
+	Keyring string
+	// SigningKey is the path to the private key that packages without
+	// signature and the database are signed with. If empty, nothing is
+	// signed.
+	SigningKey string
+	signer     *pgp.Signer
 	// Backup specifies whether to backup old packages.
 	Backup bool
 	// BackupDir specifies where old packages are backed up to.
@@ -36,13 +46,19 @@ type Repo struct {
 	// for upgrades. Explicitely specifying the file will override the
 	// ignore however.
 	IgnoreAUR []string
-
-	// AddParameters are parameters to add to the repo-add
-	// command line.
-	AddParameters []string
-	// RemoveParameters are parameters to add to the repo-remove
-	// command line.
-	RemoveParameters []string
+	// Cache is the path to the cache of package metadata, which saves
+	// reading package files that have not changed. If empty, no cache
+	// is used.
+	Cache string
+	// System is the pacman installation that dependencies are resolved
+	// against, such as the host or a clean chroot.
+	System *pacman.System
+	// PreferProviders are the names of packages that are preferred when
+	// a dependency is provided by more than one package.
+	PreferProviders []string
+	// ReplacePolicy specifies what happens when a package is added that
+	// replaces or conflicts with a package in the repository.
+	ReplacePolicy ReplacePolicy
 }
 
 // New creates a new default configuration with repo as the repository
@@ -62,16 +78,16 @@ func New(repo string) *Repo {
 		Database:  path.Base(repo),
 		BackupDir: `backup`,
 
-		IgnoreAUR:        make([]string, 0),
-		AddParameters:    make([]string, 0),
-		RemoveParameters: make([]string, 0),
+		IgnoreAUR:     make([]string, 0),
+		System:        pacman.DefaultSystem(),
+		ReplacePolicy: ReplaceWarn,
 	}
 }
 
 // NewFromConf creates a new configuration based on the configuration
 // file.
 func NewFromConf(c *conf.Configuration) (*Repo, error) {
-	p, _, err := c.SelectProfile()
+	p, name, err := c.SelectProfile()
 	if err != nil {
 		return nil, err
 	}
@@ -83,9 +99,17 @@ func NewFromConf(c *conf.Configuration) (*Repo, error) {
 	r.Backup = p.Backup
 	r.BackupDir = p.BackupDir
 	r.IgnoreAUR = p.IgnoreAUR
-	r.AddParameters = p.AddParameters
-	r.RemoveParameters = p.RemoveParameters
 	r.RequireSignature = p.RequireSignature
+	r.Keyring = p.KeyringPath(name)
+	r.SigningKey = p.SigningKey
+	r.System = p.PacmanSystem()
+	r.PreferProviders = p.PreferProviders
+	if p.ReplacePolicy != "" {
+		r.ReplacePolicy = ReplacePolicy(p.ReplacePolicy)
+	}
+	if !c.NoCache {
+		r.Cache = p.CachePath(name)
+	}
 	return r, nil
 }
 
@@ -101,6 +125,12 @@ func (r *Repo) DatabasePath() string {
 	return filepath.Join(r.Directory, r.Database)
 }
 
+// FilesDatabasePath returns the entire path to the files database,
+// which is kept alongside the database.
+func (r *Repo) FilesDatabasePath() string {
+	return pacman.FilesDatabasePath(r.DatabasePath())
+}
+
 // IgnoreFltr returns a FilterFunc for filtering out packages that should
 // be ignored. For example, for a list of meta.Packages:
 //
@@ -0,0 +1,132 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"os"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/pacman/pgp"
+	"github.com/goulash/errs"
+	"github.com/goulash/osutil"
+)
+
+// PassphraseEnv is the environment variable that the passphrase of an
+// encrypted signing key is read from.
+const PassphraseEnv = "REPOCTL_SIGNING_PASSPHRASE"
+
+// ErrNoSigningKey is returned when signing is requested, but the
+// repository has no signing key.
+var ErrNoSigningKey = errors.New("no signing key configured (set signing_key in the profile)")
+
+// Signer returns the signer for the repository signing key. If the
+// repository has no signing key, nil is returned without error.
+// The key is only read once.
+func (r *Repo) Signer() (*pgp.Signer, error) {
+	if r.SigningKey == "" {
+		return nil, nil
+	}
+	if r.signer == nil {
+		s, err := pgp.ReadSigner(r.SigningKey, []byte(os.Getenv(PassphraseEnv)))
+		if err != nil {
+			return nil, err
+		}
+		r.signer = s
+	}
+	return r.signer, nil
+}
+
+// signFile creates a detached signature for the file at path.
+func signFile(s *pgp.Signer, path string) error {
+	term.Printf("Signing: %s\n", path)
+	return s.SignFile(path)
+}
+
+// SignDatabase signs the database and the files database, if the
+// repository has a signing key. Otherwise, any existing signatures of the
+// databases are removed, since they are no longer valid after the
+// databases have changed.
+func (r *Repo) SignDatabase() error {
+	s, err := r.Signer()
+	if err != nil {
+		return err
+	}
+	for _, dbpath := range []string{r.DatabasePath(), r.FilesDatabasePath()} {
+		if ex, _ := osutil.FileExists(dbpath); !ex {
+			continue
+		}
+		if s == nil {
+			if ex, _ := osutil.FileExists(dbpath + ".sig"); ex {
+				term.Warnf("Warning: removing outdated database signature: %s.sig\n", dbpath)
+				if err := os.Remove(dbpath + ".sig"); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := signFile(s, dbpath); err != nil {
+			return err
+		}
+		if err := pacman.LinkDatabaseSignature(dbpath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sign creates signatures for the newest package files of the given
+// names that do not have one yet. If no names are given, all packages in
+// the repository are considered. Packages that are registered in the
+// database are added again, so that the database contains their signature.
+func (r *Repo) Sign(h errs.Handler, pkgnames ...string) error {
+	errs.Init(&h)
+
+	s, err := r.Signer()
+	if err != nil {
+		return err
+	} else if s == nil {
+		return ErrNoSigningKey
+	}
+
+	pkgs, err := r.ReadMeta(h, pkgnames...)
+	if err != nil {
+		return err
+	}
+
+	var readd []string
+	for _, p := range pkgs {
+		if !p.HasFiles() {
+			continue
+		}
+		spkg, err := NewSignedPkg(p.Pkg().Filename)
+		if err != nil {
+			if err = h(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if spkg.HasSignature() {
+			term.Debugf("Already signed: %s\n", spkg.PkgFile)
+			continue
+		}
+		if err := signFile(s, spkg.PkgFile); err != nil {
+			if err = h(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.IsRegistered() && p.Database.Filename == spkg.PkgFile {
+			readd = append(readd, spkg.PkgFile)
+		}
+	}
+
+	if len(readd) == 0 {
+		term.Debugf("Nothing to add to the database.\n")
+		return nil
+	}
+	return r.ModifyDatabase(readd, nil)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// writeTestSigningKey writes a new private key without passphrase to dir
+// and returns its path.
+func writeTestSigningKey(t *testing.T, dir string) string {
+	t.Helper()
+	e, err := openpgp.NewEntity("repo", "", "repo@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	path := filepath.Join(dir, "signing.asc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+	w, err := armor.Encode(f, openpgp.PrivateKeyType, nil)
+	if err == nil {
+		err = e.SerializePrivate(w, nil)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return path
+}
+
+func TestSignUnsignedPackages(t *testing.T) {
+	tests := []struct {
+		require bool
+		want    []string
+	}{
+		{false, []string{"bar", "foo"}},
+		// Packages must be signed by a trusted key to be added, so they
+		// are not signed with the repository key instead.
+		{true, nil},
+	}
+	for _, tc := range tests {
+		r := newTestRepo(t, nil, nil)
+		r.SigningKey = writeTestSigningKey(t, t.TempDir())
+		r.Keyring = filepath.Join(t.TempDir(), "keyring.asc")
+		r.RequireSignature = tc.require
+
+		src := t.TempDir()
+		if err := r.Copy(nil, writeTestPackage(t, src, testPkg("foo", "1.0-1"))); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		// Packages that are put in the repository directory by hand are
+		// treated the same by Update.
+		writeTestPackage(t, r.Directory, testPkg("bar", "1.0-1"))
+		if err := r.Update(nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		pkgs, err := r.ReadDatabase()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		if !equalStrings(names, tc.want) {
+			t.Errorf("require signature %v: expected %v in database, got %v", tc.require, tc.want, names)
+		}
+		sigs, _ := filepath.Glob(filepath.Join(r.Directory, "*.pkg.tar.gz.sig"))
+		if len(sigs) != len(tc.want) {
+			t.Errorf("require signature %v: expected %d package signatures, got %v", tc.require, len(tc.want), sigs)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	pu "github.com/cassava/repoctl/pacman/pkgutil"
+	"github.com/goulash/errs"
+)
+
+// ErrNotInDatabase is passed to the error handler for package names that
+// were asked for, but which are not in the database.
+var ErrNotInDatabase = errors.New("package not in database")
+
+// Verify checks that the package files registered in the database have
+// the size and checksums that the database advertises. If pkgnames is
+// empty, all packages in the database are checked.
+//
+// Every package that fails verification results in a *pacman.IntegrityError
+// that is passed to h. Names in pkgnames that are not in the database result
+// in an error wrapping ErrNotInDatabase.
+func (r *Repo) Verify(h errs.Handler, pkgnames ...string) error {
+	errs.Init(&h)
+
+	pkgs, err := r.ReadDatabase()
+	if err != nil {
+		return err
+	}
+	if len(pkgnames) != 0 {
+		pkgs = pu.Filter(pkgs, pu.NameFltr(pkgnames)).(pacman.Packages)
+		if err := missingNames(h, pkgs, pkgnames); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range pkgs {
+		err := pacman.VerifyIntegrity(p)
+		if err == nil {
+			term.Debugf("Verified: %s\n", p.Filename)
+			continue
+		}
+		if err = h(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingNames passes an error wrapping ErrNotInDatabase to h for every
+// name in pkgnames that is not the name of one of pkgs.
+func missingNames(h errs.Handler, pkgs pacman.Packages, pkgnames []string) error {
+	found := make(map[string]bool)
+	for _, p := range pkgs {
+		found[p.Name] = true
+	}
+	for _, n := range pkgnames {
+		if found[n] {
+			continue
+		}
+		if err := h(fmt.Errorf("%w: %s", ErrNotInDatabase, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,7 +19,9 @@ var resetCmd = &cobra.Command{
 	Long: `Delete the repository database and re-add all packages in repository.
 
   Essentially, this command deletes the repository database and recreates it by
-  running the update command.
+  running the update command. If the profile has a signing key, package files
+  that are not signed yet are signed before they are added, and the new
+  databases are signed as well.
 
   If the repository does not exist yet, then it is initialized.
 `,
@@ -51,6 +53,14 @@ var resetCmd = &cobra.Command{
 			return err
 		}
 
+		// Sign unsigned packages, so that their signatures are in the database
+		if Repo.SigningKey != "" {
+			err = Repo.Sign(nil)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Populate the database with packages
 		return Repo.Update(nil)
 	},
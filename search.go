@@ -6,7 +6,9 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/cassava/repoctl/internal/term"
 	"github.com/cassava/repoctl/pacman/aur"
@@ -18,6 +20,9 @@ var (
 	searchSortBy string
 	searchRaw    bool
 	searchInfo   bool
+	searchBy     string
+	searchAnd    bool
+	searchRegex  string
 )
 
 func init() {
@@ -28,17 +33,45 @@ func init() {
 		return []string{"name", "votes", "popularity", "votes-reverse", "popularity-reverse"}, cobra.ShellCompDirectiveDefault
 	})
 
+	searchCmd.Flags().StringVar(&searchBy, "by", "name", "which field to search by")
+	searchCmd.RegisterFlagCompletionFunc("by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return aur.SearchFields, cobra.ShellCompDirectiveNoFileComp
+	})
+	searchCmd.Flags().BoolVar(&searchAnd, "and", false, "show only packages that match every term")
+	searchCmd.Flags().StringVarP(&searchRegex, "regex", "e", "", "show only packages whose name or description match")
+
 	searchCmd.Flags().BoolVarP(&searchRaw, "raw", "r", false, "show only the name")
 	searchCmd.Flags().BoolVarP(&searchInfo, "info", "i", false, "show package information")
 }
 
 var searchCmd = &cobra.Command{
-	Use:   "search [PKGNAME ...]",
+	Use:   "search TERM...",
 	Short: "Search for packages on AUR",
 	Long: `Search for packages hosted on AUR.
 
-  This command searches the specified arguments on AUR by the name property.
-  The results are combined and sorted by one of the following methods:
+  This command searches the specified terms on AUR by the name property,
+  or by the field given with --by, which is one of:
+
+    name            name contains the term
+    name-desc       name or description contain the term
+    maintainer      maintainer is the term
+    submitter       submitter is the term
+    depends         depends on the term
+    makedepends     make-depends on the term
+    optdepends      optionally depends on the term
+    checkdepends    check-depends on the term
+    provides        is or provides the term
+    conflicts       conflicts with the term
+    replaces        replaces the term
+    groups          is in the group
+    keywords        has the keyword
+
+  The results of all terms are combined, or with --and, only packages
+  that match every term are kept. With --regex, only packages whose name
+  or description match the regular expression are kept; this is useful,
+  since the AUR itself does not support regular expressions.
+
+  The results are sorted by one of the following methods:
 
     name
     votes
@@ -52,18 +85,36 @@ var searchCmd = &cobra.Command{
   also be expanded to include other metadata by using the --info flag.
 `,
 	Example: `  repoctl search --sort-by=votes firefox
-  repoctl search flir flirc flirc-bin`,
+  repoctl search flir flirc flirc-bin
+  repoctl search --by=maintainer cassava
+  repoctl search --by=provides java-runtime
+  repoctl search --by=depends --and qt5-base python
+  repoctl search --by=name-desc --regex='^python-.*-git$' python`,
+	Args:              cobra.MinimumNArgs(1),
 	ValidArgsFunction: completeNoFiles,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		exceptQuiet()
 
-		var pkgs aur.Packages
-		for _, q := range args {
-			aurpkgs, err := aur.SearchByName(q)
+		if !aur.IsSearchField(searchBy) {
+			return fmt.Errorf("unknown search field %q, must be one of: %s", searchBy, strings.Join(aur.SearchFields, ", "))
+		}
+		var re *regexp.Regexp
+		if searchRegex != "" {
+			var err error
+			re, err = regexp.Compile(searchRegex)
 			if err != nil {
-				return err
+				return fmt.Errorf("invalid regular expression: %w", err)
 			}
-			pkgs = append(pkgs, aurpkgs...)
+		}
+
+		pkgs, err := searchAUR(searchBy, args, searchAnd)
+		if err != nil {
+			return err
+		}
+		if re != nil {
+			pkgs = filterAURPackages(pkgs, func(p *aur.Package) bool {
+				return re.MatchString(p.Name) || re.MatchString(p.Description)
+			})
 		}
 
 		// Sort the list
@@ -122,3 +173,44 @@ var searchCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// searchAUR searches AUR for each of the terms by the field and returns
+// the combined results, each package only once. If and is true, only the
+// packages that are found for every term are returned.
+func searchAUR(by string, terms []string, and bool) (aur.Packages, error) {
+	var pkgs aur.Packages
+	seen := make(map[string]bool)
+	for i, q := range terms {
+		aurpkgs, err := aur.Search(by, q)
+		if err != nil {
+			return nil, fmt.Errorf("cannot search AUR for %q: %w", q, err)
+		}
+		if !and || i == 0 {
+			for _, p := range aurpkgs {
+				if !seen[p.Name] {
+					seen[p.Name] = true
+					pkgs = append(pkgs, p)
+				}
+			}
+			continue
+		}
+
+		found := make(map[string]bool)
+		for _, p := range aurpkgs {
+			found[p.Name] = true
+		}
+		pkgs = filterAURPackages(pkgs, func(p *aur.Package) bool { return found[p.Name] })
+	}
+	return pkgs, nil
+}
+
+// filterAURPackages returns the packages for which keep returns true.
+func filterAURPackages(pkgs aur.Packages, keep func(*aur.Package) bool) aur.Packages {
+	result := make(aur.Packages, 0, len(pkgs))
+	for _, p := range pkgs {
+		if keep(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	MainCmd.AddCommand(signCmd)
+}
+
+var signCmd = &cobra.Command{
+	Use:   "sign [PKGNAME ...]",
+	Short: "Sign packages in the repository",
+	Long: `Create signatures for packages in the repository that have none.
+
+  The newest package file of each package given is signed with the
+  signing_key of the current profile, if it is not signed yet. If no
+  packages are given, all packages in the repository are considered.
+  Afterwards, the database and the files database are updated and signed.
+
+  Packages without signature are also signed automatically when they
+  are added to the repository, so this command is mostly useful after
+  configuring a signing key for an existing repository.
+
+  If the signing key is encrypted, the passphrase is read from the
+  environment variable REPOCTL_SIGNING_PASSPHRASE.
+`,
+	Example: `  repoctl sign
+  repoctl sign fairsplit`,
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return Repo.Sign(nil, args...)
+	},
+}
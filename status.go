@@ -56,6 +56,11 @@ var statusCmd = &cobra.Command{
 			if err != nil && !aur.IsNotFound(err) {
 				return err
 			}
+			aurpkgs := make(aur.Packages, len(pkgs))
+			for i, p := range pkgs {
+				aurpkgs[i] = p.AUR
+			}
+			warnStaleAUR(aurpkgs)
 		}
 
 		// We assume that there is nothing to do, and if there is,
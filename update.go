@@ -31,6 +31,10 @@ var updateCmd = &cobra.Command{
   If backup is true, obsolete files are backup up instead of deleted.
   If the backup directory resolves to the repository directory,
   then obsolete package files are ignored.
+
+  As with add, the replace_policy option of the profile decides what
+  happens when a package replaces or conflicts with another package in
+  the repository, including the other packages that are updated.
 `,
 	Example:           `  repoctl update fairsplit`,
 	ValidArgsFunction: completeRepoPackageNames,
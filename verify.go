@@ -0,0 +1,77 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman"
+	"github.com/cassava/repoctl/repo"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	MainCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [PKGNAME ...]",
+	Short: "Verify package files against the database",
+	Long: `Verify that package files match what the database says about them.
+
+  For each package registered in the database, the size and checksum of
+  the package file are compared with the database. If no packages are
+  given, all packages in the database are verified. The following
+  problems are reported:
+
+    "missing":   the package file does not exist
+    "truncated": the package file is smaller than it should be
+    "corrupted": the package file has the wrong size or checksum
+    "swapped":   the package file contains another package
+    "signature mismatch": the signature file differs from the database
+    "signature missing":  the database has a signature, but there is no
+                          signature file next to the package
+
+  If any package fails verification, or if a given package is not in the
+  database, repoctl exits with a non-zero status, which makes this command
+  suitable for running regularly.
+`,
+	Example: `  repoctl verify
+  repoctl verify -q || mail -s "repository damaged" root`,
+	ValidArgsFunction: completeRepoPackageNames,
+	PreRunE:           ProfileInit,
+	PostRunE:          ProfileTeardown,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var failed int
+		err := Repo.Verify(func(err error) error {
+			if errors.Is(err, repo.ErrNotInDatabase) {
+				failed++
+				term.Errorf("%s\n", err)
+				return nil
+			}
+			var ierr *pacman.IntegrityError
+			if !errors.As(err, &ierr) {
+				return err
+			}
+			failed++
+			term.Errorf("%s: @{!r}%s@|", ierr.Filename, ierr.Problem)
+			if ierr.Detail != "" {
+				term.Errorf(" (%s)", ierr.Detail)
+			}
+			term.Errorf("\n")
+			return nil
+		}, args...)
+		if err != nil {
+			return err
+		}
+		if failed != 0 {
+			return fmt.Errorf("%d packages failed verification", failed)
+		}
+		term.Printf("All package files verified.\n")
+		return nil
+	},
+}
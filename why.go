@@ -0,0 +1,71 @@
+// Copyright (c) 2026, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/cassava/repoctl/internal/term"
+	"github.com/cassava/repoctl/pacman/graph"
+	"github.com/cassava/repoctl/repo"
+	"github.com/spf13/cobra"
+)
+
+var whyInstalled bool
+
+func init() {
+	MainCmd.AddCommand(whyCmd)
+
+	whyCmd.Flags().BoolVarP(&whyInstalled, "installed", "i", false, "include installed packages")
+}
+
+var whyCmd = &cobra.Command{
+	Use:   "why DEP PKGNAME...",
+	Short: "Explain why a package is a dependency of AUR packages",
+	Long: `Explain why a package is a dependency of AUR packages.
+
+  The dependencies of the given AUR packages are resolved in the same way
+  as for the down command, and the shortest chains of dependencies that
+  lead from each of them to DEP are printed, one per line. Dependencies
+  that are not installation dependencies are marked with their kind.
+
+  DEP is the name of the package that is pulled in, not of a package that
+  it provides. By default, installed packages are not part of the graph,
+  so use --installed to explain why an installed package is needed.
+`,
+	Example: `  repoctl why python-setuptools some-aur-package
+  repoctl why -i glibc foo bar`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeAURPackageNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dep, pkgnames := args[0], args[1:]
+		g, err := dependencyGraph(repo.GraphOptions{Installed: whyInstalled}, pkgnames)
+		if err != nil {
+			return err
+		}
+		if !g.HasName(dep) {
+			return fmt.Errorf("%s is not a dependency of the given packages", dep)
+		}
+
+		found := false
+		for _, name := range pkgnames {
+			if name == dep {
+				term.Printf("%s was requested\n", name)
+				found = true
+				continue
+			}
+			for _, p := range graph.ShortestPaths(g, name, dep) {
+				term.Printf("%s\n", p)
+				found = true
+			}
+		}
+		if !found {
+			// This can happen when a package is given by a name that it
+			// is not known by on AUR, such as a member of a split package.
+			return fmt.Errorf("%s is not a dependency of the given packages", dep)
+		}
+		return nil
+	},
+}